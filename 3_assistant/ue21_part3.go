@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	. "time"
+
+	"github.com/u-ways/go-channels/clinic"
+)
+
+// 3.b.
+//   "What would be a deadlock possibility that affects part 2 but not part 3?"
+//
+//   > Deadlock is where no process proceeds, and get blocked.
+//
+//   1. Dentist have one low priority patient
+//   2. Dentist checks the high priority queue first => found no one
+//   3. Low priority patient has aged => move to high priority queue
+//   4. Dentist checks the low priority queue => found no one
+//   5. Dentist goes to sleep... (deadlock)
+//
+//   This is fixed in part 3 because the dentist has one "waiting" queue only.
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Main Method                                                                                             //
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func main() {
+	const maxThreads = 5
+	runtime.GOMAXPROCS(maxThreads)
+
+	// closes the clinic early on Ctrl+C
+	ctx, closeClinic := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer closeClinic()
+
+	const lwaitChannelSize = 10
+	const hwaitChannelSize = 20
+	const waitChannelSize = 15
+
+	const numberOfDentists = 1
+	const lPatients = 10
+	const hPatients = 20
+
+	// The clinic closes as soon as the last patient leaves, or at the
+	// latest once the time we used to wait for all of them is over
+	ctx, closingTime := context.WithTimeout(ctx, 5*(hPatients+lPatients)*Second)
+	defer closingTime()
+
+	report, err := clinic.Run(ctx, clinic.Config{
+		Scenario: clinic.AssistantScenario,
+		Dentists: numberOfDentists,
+		// asynchronous waiting rooms for wait, hwait and lwait
+		WaitSize:     waitChannelSize,
+		HWaitSize:    hwaitChannelSize,
+		LWaitSize:    lwaitChannelSize,
+		AgingLimit:   500 * Millisecond,
+		LowPatients:  lPatients,
+		HighPatients: hPatients,
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	clinic.ReportLog(report)
+}
@@ -0,0 +1,120 @@
+package clinic
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/** appointments **********************************************************/
+
+/**
+ * A patient booked for a slot, on top of the walk-ins.
+ */
+type Appointment struct {
+	// When the patient is booked for, from the clinic opening
+	Slot time.Duration
+	// The priority level the patient is triaged into
+	Priority Priority
+	// What the patient is booked for, drawn from the run's treatment mix when empty
+	Treatment Treatment
+}
+
+/**
+ * An appointment book lists the patients booked ahead, and how they keep
+ * their appointments. A booked patient walking in by their slot (give or
+ * take Tolerance) is seen at their slot, ahead of every walk-in. Patients
+ * turning up later than that wait along with the walk-ins.
+ */
+type AppointmentBook struct {
+	Booked []Appointment
+	// How long before their slot booked patients walk in, and how late
+	// they may be on top of that (never when left empty)
+	Early    time.Duration
+	Lateness DurationModel
+	// How late a patient can walk in and still be seen at their slot
+	Tolerance time.Duration
+	// The chance a booked patient never shows up, between 0 and 1
+	NoShows float64
+}
+
+/**
+ * Books count patients at a priority level, one slot every interval from
+ * first. Nobody is booked when count is not positive.
+ */
+func Slots(first time.Duration, interval time.Duration, count int, priority Priority) []Appointment {
+	appointments := make([]Appointment, max(count, 0))
+	for i := range appointments {
+		appointments[i] = Appointment{Slot: first + time.Duration(i)*interval, Priority: priority}
+	}
+	return appointments
+}
+
+/**
+ * When a booked patient walks in, from the clinic opening, and whether
+ * they show up at all. Both are always drawn, so a no-show never shifts
+ * the draws of the patients booked after them.
+ */
+func (book AppointmentBook) turnUp(appointment Appointment, random *rand.Rand) (time.Duration, bool) {
+	shows := random.Float64() >= book.NoShows
+	late := time.Duration(0)
+	if book.Lateness != nil {
+		late = book.Lateness.Duration(random)
+	}
+	return max(appointment.Slot-book.Early+late, 0), shows
+}
+
+/**
+ * Whether a booked patient walking in at arrived missed their slot
+ */
+func (book AppointmentBook) late(slot time.Time, arrived time.Time) bool {
+	return !slot.IsZero() && arrived.After(slot.Add(book.Tolerance))
+}
+
+/**
+ * How many booked patients walked in, on time or late
+ */
+func (report Report) booked() int {
+	booked := 0
+	for _, patient := range report.Patients {
+		if !patient.Slot.IsZero() {
+			booked++
+		}
+	}
+	return booked
+}
+
+/** parsing **********************************************************/
+
+/**
+ * Parses booked slots as "count,interval" or "count,interval,first", e.g.
+ * "6,10m" books six patients ten minutes apart, the first at 10m (the
+ * first slot is one interval after the opening unless given).
+ */
+func ParseSlots(spec string, priority Priority) ([]Appointment, error) {
+	arguments := strings.Split(spec, ",")
+	if len(arguments) < 2 || len(arguments) > 3 {
+		return nil, fmt.Errorf("clinic: appointments %q are not count,interval[,first]", spec)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(arguments[0]))
+	if err != nil {
+		return nil, fmt.Errorf("clinic: appointments %q %w", spec, err)
+	}
+	interval, err := time.ParseDuration(strings.TrimSpace(arguments[1]))
+	if err != nil {
+		return nil, fmt.Errorf("clinic: appointments %q %w", spec, err)
+	}
+	first := interval
+	if len(arguments) == 3 {
+		if first, err = time.ParseDuration(strings.TrimSpace(arguments[2])); err != nil {
+			return nil, fmt.Errorf("clinic: appointments %q %w", spec, err)
+		}
+	}
+	if count < 0 || interval < 0 || first < 0 {
+		return nil, fmt.Errorf("clinic: appointments %q must not be negative", spec)
+	}
+	return Slots(first, interval, count, priority), nil
+}
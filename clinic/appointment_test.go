@@ -0,0 +1,136 @@
+package clinic
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSlots(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		slots []time.Duration
+		fails bool
+	}{
+		{spec: "3,10s", slots: []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}},
+		{spec: "2, 1m, 5s", slots: []time.Duration{5 * time.Second, time.Minute + 5*time.Second}},
+		{spec: "0,10s", slots: []time.Duration{}},
+		{spec: "-1,10s", fails: true},
+		{spec: "2,-10s", fails: true},
+		{spec: "2,10s,-1s", fails: true},
+		{spec: "2", fails: true},
+		{spec: "2,10s,1s,1s", fails: true},
+		{spec: "two,10s", fails: true},
+		{spec: "2,ten", fails: true},
+	} {
+		appointments, err := ParseSlots(test.spec, 1)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseSlots(%q) = %v, want an error", test.spec, appointments)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSlots(%q) failed: %v", test.spec, err)
+			continue
+		}
+
+		slots := []time.Duration{}
+		for _, appointment := range appointments {
+			slots = append(slots, appointment.Slot)
+			if appointment.Priority != 1 {
+				t.Errorf("ParseSlots(%q) booked priority %d, want 1", test.spec, appointment.Priority)
+			}
+		}
+		if !slices.Equal(slots, test.slots) {
+			t.Errorf("ParseSlots(%q) booked %v, want %v", test.spec, slots, test.slots)
+		}
+	}
+}
+
+/**
+ * Twenty walk-ins in at the opening, treated a second each, and one
+ * patient booked at 5.5s, walking in as the book tells
+ */
+func bookedAhead(scenario Scenario, capacity int, book AppointmentBook) Config {
+	book.Booked = Slots(5500*time.Millisecond, 0, 1, 0)
+	return Config{
+		Scenario:     scenario,
+		WaitSize:     capacity,
+		HWaitSize:    capacity,
+		LWaitSize:    capacity,
+		LowPatients:  20,
+		Arrivals:     FixedInterval(0),
+		Durations:    Durations{Default: Fixed(time.Second)},
+		Appointments: book,
+		Clock:        NewVirtualClock(time.Unix(0, 0)),
+		Seed:         1,
+		Sink:         Sinks{},
+	}
+}
+
+func TestBookedPatientsAreSeenAtTheirSlot(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		capacity int
+		book     AppointmentBook
+		// When the booked patient walks in and is seen, from the opening
+		arrived time.Duration
+		started time.Duration
+		// The dentist of part 3 takes them out of lwait, where they stand first, when it differs
+		assisted time.Duration
+		late     bool
+	}{
+		{name: "on time", capacity: 20, arrived: 5500 * time.Millisecond, started: 6 * time.Second},
+		{name: "a little late", capacity: 20, book: AppointmentBook{Lateness: Fixed(300 * time.Millisecond), Tolerance: time.Second},
+			arrived: 5800 * time.Millisecond, started: 6 * time.Second},
+		{name: "too late", capacity: 20, book: AppointmentBook{Lateness: Fixed(2 * time.Second), Tolerance: time.Second},
+			arrived: 7500 * time.Millisecond, started: 20 * time.Second, late: true},
+		// Standing ahead of the walk-ins, but still one treatment away from a seat
+		{name: "on time in a full room", capacity: 5, arrived: 5500 * time.Millisecond, started: 7 * time.Second, assisted: 6 * time.Second},
+	} {
+		for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+			t.Run(test.name+"/"+scenario.String(), func(t *testing.T) {
+				started := test.started
+				if scenario == AssistantScenario && test.assisted > 0 {
+					started = test.assisted
+				}
+				report, err := Run(context.Background(), bookedAhead(scenario, test.capacity, test.book))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(report.Treated) != 21 {
+					t.Fatalf("treated %v, want every patient", report.Treated)
+				}
+
+				opened := time.Unix(0, 0)
+				booked := report.Patients[20]
+				if arrived, seen := booked.Arrived.Sub(opened), booked.Started.Sub(opened); arrived != test.arrived || seen != started {
+					t.Errorf("booked patient walked in at %v and was seen at %v, want %v and %v", arrived, seen, test.arrived, started)
+				}
+				if booked.Late != test.late || slices.Equal(report.Late, []int{21}) != test.late {
+					t.Errorf("booked patient late %v (late patients %v), want %v", booked.Late, report.Late, test.late)
+				}
+			})
+		}
+	}
+}
+
+func TestBookedPatientsNotShowingUp(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		report, err := Run(context.Background(), bookedAhead(scenario, 20, AppointmentBook{NoShows: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(report.NoShows, []int{21}) || len(report.Treated) != 20 || len(report.Patients) != 20 {
+			t.Errorf("%s: no-shows %v and treated %v, want the booked patient missing and every walk-in treated", scenario, report.NoShows, report.Treated)
+		}
+	}
+
+	for _, noShows := range []float64{-0.1, 1.5} {
+		if _, err := Run(context.Background(), bookedAhead(DentistScenario, 20, AppointmentBook{NoShows: noShows})); err == nil {
+			t.Errorf("a %g chance of no-shows ran, want an error", noShows)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package clinic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/** arrival processes **********************************************/
+
+/**
+ * An arrival process tells when patients walk into the clinic. The first
+ * patient arrives Config.Opening after the clinic opens (unless the
+ * process is a FirstArrival), and Gap draws how long after patient n-1
+ * patient n arrives (for n ≥ 1).
+ */
+type ArrivalProcess interface {
+	Gap(n int, random *rand.Rand) time.Duration
+}
+
+/**
+ * An arrival process telling when the first patient arrives itself, after
+ * the clinic opens, in place of Config.Opening.
+ */
+type FirstArrival interface {
+	ArrivalProcess
+	First() time.Duration
+}
+
+/**
+ * Patients arrive one every interval, or all at once for an interval of 0.
+ */
+type FixedInterval time.Duration
+
+func (process FixedInterval) Gap(int, *rand.Rand) time.Duration {
+	return time.Duration(process)
+}
+
+/**
+ * Patients arrive independently of each other, Rate patients per second on
+ * average, so the gaps between two arrivals are exponentially distributed.
+ */
+type Poisson struct {
+	Rate float64
+}
+
+func (process Poisson) Gap(_ int, random *rand.Rand) time.Duration {
+	if process.Rate <= 0 {
+		return 0
+	}
+	return positive(random.ExpFloat64() / process.Rate * float64(time.Second))
+}
+
+/**
+ * Patients arrive in batches of Size at once, one batch every Interval.
+ */
+type Batch struct {
+	Size     int
+	Interval time.Duration
+}
+
+func (process Batch) Gap(n int, _ *rand.Rand) time.Duration {
+	if process.Size <= 1 || n%process.Size == 0 {
+		return process.Interval
+	}
+	return 0
+}
+
+/**
+ * Patients arrive at recorded times, given as offsets from the clinic
+ * opening in ascending order, the first one included. A run replaying
+ * fewer arrivals than it has patients is rejected.
+ */
+type Replay []time.Duration
+
+func (process Replay) Gap(n int, _ *rand.Rand) time.Duration {
+	if n >= len(process) {
+		return 0
+	}
+	return max(process[n]-process[n-1], 0)
+}
+
+func (process Replay) First() time.Duration {
+	if len(process) == 0 {
+		return 0
+	}
+	return process[0]
+}
+
+/**
+ * Reads recorded arrival times from a file, one offset from the opening
+ * per line either as a duration ("1.5s") or in seconds ("1.5"). Blank
+ * lines and lines starting with # are skipped.
+ */
+func LoadReplay(path string) (Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var replay Replay
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		offset, err := time.ParseDuration(text)
+		if err != nil {
+			seconds, parseErr := strconv.ParseFloat(text, 64)
+			if parseErr != nil {
+				return nil, fmt.Errorf("clinic: %s:%d: %q is not an arrival time", path, line, text)
+			}
+			offset = time.Duration(seconds * float64(time.Second))
+		}
+		if len(replay) > 0 && offset < replay[len(replay)-1] {
+			return nil, fmt.Errorf("clinic: %s:%d: arrival times must be in ascending order", path, line)
+		}
+		replay = append(replay, offset)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+/**
+ * Parses an arrival process from its name and parameters, as in:
+ *   • "fixed:1s" for FixedInterval(1s)
+ *   • "poisson:0.5" for Poisson{Rate: 0.5} (patients per second)
+ *   • "batch:5,10s" for Batch{Size: 5, Interval: 10s}
+ *   • "replay:arrivals.txt" for the arrival times recorded in a file
+ *
+ * Rates and batch sizes must be positive, and intervals not negative.
+ */
+func ParseArrivalProcess(spec string) (ArrivalProcess, error) {
+	name, parameters, _ := strings.Cut(spec, ":")
+	arguments := strings.Split(parameters, ",")
+
+	var err error
+	arity := func(count int) bool {
+		if len(arguments) != count {
+			err = fmt.Errorf("takes %d parameters", count)
+		}
+		return err == nil
+	}
+	duration := func(i int) (parsed time.Duration) {
+		if err == nil {
+			parsed, err = time.ParseDuration(strings.TrimSpace(arguments[i]))
+		}
+		return parsed
+	}
+	number := func(i int) (parsed float64) {
+		if err == nil {
+			parsed, err = strconv.ParseFloat(strings.TrimSpace(arguments[i]), 64)
+		}
+		return parsed
+	}
+	integer := func(i int) (parsed int) {
+		if err == nil {
+			parsed, err = strconv.Atoi(strings.TrimSpace(arguments[i]))
+		}
+		return parsed
+	}
+
+	var process ArrivalProcess
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fixed":
+		if arity(1) {
+			process = FixedInterval(duration(0))
+			if err == nil && process.(FixedInterval) < 0 {
+				err = errors.New("must not have a negative interval")
+			}
+		}
+	case "poisson":
+		if arity(1) {
+			process = Poisson{Rate: number(0)}
+			if err == nil && !(process.(Poisson).Rate > 0) {
+				err = errors.New("must have a positive rate")
+			}
+		}
+	case "batch":
+		if arity(2) {
+			batch := Batch{Size: integer(0), Interval: duration(1)}
+			switch {
+			case err != nil:
+			case batch.Size < 1:
+				err = errors.New("must have a positive size")
+			case batch.Interval < 0:
+				err = errors.New("must not have a negative interval")
+			}
+			process = batch
+		}
+	case "replay":
+		// the file name is taken as is, commas included
+		replay, err := LoadReplay(strings.TrimSpace(parameters))
+		if err != nil {
+			return nil, err
+		}
+		return replay, nil
+	default:
+		err = errors.New("is unknown")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("clinic: arrival process %q %w", spec, err)
+	}
+	return process, nil
+}
+
+func (process FixedInterval) String() string {
+	return fmt.Sprintf("fixed:%s", time.Duration(process))
+}
+
+func (process Poisson) String() string {
+	return fmt.Sprintf("poisson:%g", process.Rate)
+}
+
+func (process Batch) String() string {
+	return fmt.Sprintf("batch:%d,%s", process.Size, process.Interval)
+}
+
+func (process Replay) String() string {
+	return fmt.Sprintf("replay:(%d arrivals)", len(process))
+}
@@ -0,0 +1,132 @@
+package clinic
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseArrivalProcess(t *testing.T) {
+	replay := filepath.Join(t.TempDir(), "arrivals.txt")
+	if err := os.WriteFile(replay, []byte("# recorded\n5s\n\n6.5\n7s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unordered := filepath.Join(t.TempDir(), "unordered.txt")
+	if err := os.WriteFile(unordered, []byte("5s\n4s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		spec    string
+		process ArrivalProcess
+		fails   bool
+	}{
+		{spec: "fixed:1s", process: FixedInterval(time.Second)},
+		{spec: "FIXED: 250ms", process: FixedInterval(250 * time.Millisecond)},
+		{spec: "poisson:0.5", process: Poisson{Rate: 0.5}},
+		{spec: "batch:5,10s", process: Batch{Size: 5, Interval: 10 * time.Second}},
+		{spec: "replay:" + replay, process: Replay{5 * time.Second, 6500 * time.Millisecond, 7 * time.Second}},
+		{spec: "fixed", fails: true},
+		{spec: "fixed:1s,2s", fails: true},
+		{spec: "poisson:fast", fails: true},
+		{spec: "batch:5", fails: true},
+		{spec: "batch:five,10s", fails: true},
+		{spec: "fixed:0s", process: FixedInterval(0)},
+		{spec: "fixed:-1s", fails: true},
+		{spec: "poisson:0", fails: true},
+		{spec: "poisson:-1", fails: true},
+		{spec: "poisson:NaN", fails: true},
+		{spec: "batch:0,5s", fails: true},
+		{spec: "batch:5,-5s", fails: true},
+		{spec: "batch:0,-5s", fails: true},
+		{spec: "replay:" + unordered, fails: true},
+		{spec: "replay:" + filepath.Join(t.TempDir(), "missing.txt"), fails: true},
+		{spec: "uniform:1s", fails: true},
+	} {
+		process, err := ParseArrivalProcess(test.spec)
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("ParseArrivalProcess(%q) = %v, want an error", test.spec, process)
+		case !test.fails && err != nil:
+			t.Errorf("ParseArrivalProcess(%q) failed: %v", test.spec, err)
+		case !test.fails:
+			if recorded, replayed := process.(Replay); replayed {
+				if !slices.Equal(recorded, test.process.(Replay)) {
+					t.Errorf("ParseArrivalProcess(%q) = %v, want %v", test.spec, recorded, test.process)
+				}
+			} else if process != test.process {
+				t.Errorf("ParseArrivalProcess(%q) = %v, want %v", test.spec, process, test.process)
+			}
+		}
+	}
+}
+
+func TestArrivalGaps(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		process ArrivalProcess
+		gaps    []time.Duration
+	}{
+		{FixedInterval(time.Second), []time.Duration{time.Second, time.Second, time.Second}},
+		{Batch{Size: 2, Interval: time.Minute}, []time.Duration{0, time.Minute, 0}},
+		{Replay{5 * time.Second, 6 * time.Second, 8 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 0}},
+	} {
+		var gaps []time.Duration
+		for n := 1; n <= len(test.gaps); n++ {
+			gaps = append(gaps, test.process.Gap(n, random))
+		}
+		if !slices.Equal(gaps, test.gaps) {
+			t.Errorf("%v gaps = %v, want %v", test.process, gaps, test.gaps)
+		}
+	}
+}
+
+func TestReplayArrivesAtRecordedOffsets(t *testing.T) {
+	recorder := &Recorder{}
+	config := Config{
+		Scenario:    DentistScenario,
+		WaitSize:    3,
+		LowPatients: 3,
+		Opening:     2 * time.Second,
+		Arrivals:    Replay{5 * time.Second, 6 * time.Second, 7 * time.Second},
+		Durations:   Durations{Default: Fixed(100 * time.Millisecond)},
+		Clock:       NewVirtualClock(time.Time{}),
+		Seed:        1,
+		Sink:        recorder,
+	}
+	if _, err := Run(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	var opened time.Time
+	var arrivals []time.Duration
+	for _, event := range recorder.Events() {
+		switch event.Kind {
+		case ClinicOpened:
+			opened = event.Time
+		case PatientArrived:
+			arrivals = append(arrivals, event.Time.Sub(opened))
+		}
+	}
+	if want := []time.Duration(config.Arrivals.(Replay)); !slices.Equal(arrivals, want) {
+		t.Errorf("patients arrived at %v, want %v", arrivals, want)
+	}
+}
+
+func TestReplayWithTooFewArrivalsIsRejected(t *testing.T) {
+	config := Config{
+		Scenario:    DentistScenario,
+		WaitSize:    3,
+		LowPatients: 3,
+		Arrivals:    Replay{time.Second, 2 * time.Second},
+		Clock:       NewVirtualClock(time.Time{}),
+		Sink:        Sinks{},
+	}
+	if _, err := Run(context.Background(), config); err == nil {
+		t.Error("Run replayed 2 arrivals for 3 patients, want an error")
+	}
+}
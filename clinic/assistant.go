@@ -0,0 +1,101 @@
+package clinic
+
+import (
+	"context"
+	"time"
+)
+
+/** assistant **********************************************************/
+
+/*
+ * assistant that communicates with the patients using the queues of each priority
+ * level (hwait and lwait), and communicates with the dentist using one single queue
+ * wait. The dentist will not see or act on the priority queues but only receive
+ * patients on wait.
+ *
+ * Many assistants can share the same waiting rooms. An assistant with no
+ * patient to place blocks until a room changes, rather than polling them.
+ * Booked patients are left in their room for the dentist (see booking).
+ */
+func (clinic *practice) assistant(ctx context.Context, me Actor) {
+	// Serve patients in the order the scheduling policy picks them.
+	for {
+		// Watched before looking, so a patient sitting down in between still wakes us up
+		changed := clinic.rooms.watch()
+
+		select {
+		case <-ctx.Done():
+			clinic.emit(Event{Kind: Closing, Actor: me})
+			return
+		default:
+		}
+
+		var patient *Session
+		var priority Priority
+		var found bool
+		if clinic.booking() {
+			// Booked patients are left for the dentist to see at their slot
+			patient, priority, found = clinic.rooms.walkIn()
+		} else {
+			patient, priority, found = clinic.rooms.next(clinic.clock.Now())
+		}
+		if !found {
+			// Every room is empty, wait for a patient to sit down
+			clinic.checkpoint(me, FoundNoPatient)
+			select {
+			case <-changed:
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		clinic.emit(Event{Kind: PatientPlaced, Actor: me, Patient: patient.Patient, Priority: priority})
+		clinic.checkpoint(me, TookPatient)
+		place(ctx, patient, clinic.wait)
+	}
+}
+
+/**
+ * Places a patient in the dentist waiting area, or sends them
+ * home if the clinic closes while the waiting area is full.
+ */
+func place(ctx context.Context, patient *Session, wait chan<- *Session) {
+	select {
+	case wait <- patient:
+	case <-ctx.Done():
+		patient.Reject()
+	}
+}
+
+/**
+ * Aging algorithm:
+ * Move a patient one level up (e.g. from lwait to hwait) as soon as they have
+ * waited limit in their room, so nobody waits more than limit at any level
+ * below the top (as long as the room above has a free seat).
+ */
+func (clinic *practice) age(ctx context.Context, limit time.Duration, mover Actor) {
+	timer := clinic.clock.NewTimer(limit)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		changed := clinic.rooms.watch()
+
+		// Sleep until the next patient is due, or the rooms change
+		var due <-chan time.Time
+		if deadline, pending := clinic.rooms.due(limit); pending {
+			timer.Reset(deadline.Sub(clinic.clock.Now()))
+			due = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case now := <-due:
+			for _, moved := range clinic.rooms.promote(now, limit) {
+				clinic.emit(Event{Kind: PatientAged, Actor: mover, Patient: moved.patient.Patient, Priority: moved.to})
+			}
+		}
+	}
+}
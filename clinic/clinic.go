@@ -0,0 +1,534 @@
+package clinic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+/** clinic **********************************************************/
+
+/**
+ * The scenarios a clinic can run:
+ *   • DentistScenario: patients queue in a single waiting room (part 1).
+ *   • PriorityScenario: the dentist serves a high priority waiting room before
+ *     a low priority one, aging low priority patients (part 2).
+ *   • AssistantScenario: an assistant moves patients from the high and low
+ *     priority waiting rooms into the dentist's waiting room (part 3).
+ */
+type Scenario int
+
+const (
+	DentistScenario Scenario = iota
+	PriorityScenario
+	AssistantScenario
+)
+
+var scenarioNames = map[Scenario]string{
+	DentistScenario:   "dentist",
+	PriorityScenario:  "priority",
+	AssistantScenario: "assistant",
+}
+
+func (scenario Scenario) String() string {
+	if name, known := scenarioNames[scenario]; known {
+		return name
+	}
+	return fmt.Sprintf("scenario(%d)", int(scenario))
+}
+
+/**
+ * Finds a scenario by name, e.g. "priority"
+ */
+func ParseScenario(name string) (Scenario, error) {
+	for scenario, known := range scenarioNames {
+		if known == name {
+			return scenario, nil
+		}
+	}
+	return 0, fmt.Errorf("clinic: unknown scenario %q", name)
+}
+
+/**
+ * Config describes a single clinic run.
+ */
+type Config struct {
+	Scenario Scenario
+
+	// Number of dentists working off the same waiting room, at least one
+	Dentists int
+	// Number of assistants triaging patients in the assistant scenario, at least one
+	Assistants int
+
+	// Capacity of the wait, hwait and lwait waiting rooms, at least one
+	// seat in the rooms of the scenario, though wait may have none in part 3
+	WaitSize  int
+	HWaitSize int
+	LWaitSize int
+
+	// The priority levels patients are triaged into, the least urgent first.
+	// Left empty, the low (lwait) and high (hwait) levels set up above.
+	Levels []Level
+
+	// Whether patients finding their waiting room full leave right away
+	// (balk) rather than stand for a seat, and how long after arriving
+	// patients give up waiting and leave untreated (renege), never when 0
+	Balking  bool
+	Patience time.Duration
+
+	// Which waiting patient is served next (PriorityWithAging when left empty),
+	// and how long a patient can wait in a priority level's room before being
+	// moved a level up when the policy ages patients
+	Policy     SchedulingPolicy
+	AgingLimit time.Duration
+
+	// Number of low and high priority patients. The dentist scenario has no
+	// priorities, so every patient there queues in the same waiting room.
+	LowPatients  int
+	HighPatients int
+
+	// Patients booked ahead for a slot, seen at their slot ahead of the walk-ins
+	Appointments AppointmentBook
+
+	// How long before the first patient arrives, and between two arrivals
+	// unless an arrival process tells otherwise
+	Opening         time.Duration
+	ArrivalInterval time.Duration
+	Arrivals        ArrivalProcess
+
+	// The steps every treatment goes through (StandardProtocol when left
+	// empty), along with its type's preparation, unless it has steps of its own
+	Protocol  Protocol
+	Protocols map[Treatment]Protocol
+
+	// What patients come in for (a Checkup when left empty), and how long
+	// each treatment takes (its type's duration when left empty, while a
+	// Default duration stands for every treatment without one of its own)
+	Treatments TreatmentMix
+	Durations  Durations
+
+	// The clock every timing of the run goes through, a RealClock when left empty
+	Clock Clock
+
+	// Drives all the randomness of the run, a new seed is picked when left empty
+	Seed int64
+
+	// Where the events of the run are published, the console when left empty
+	Sink Sink
+}
+
+/**
+ * Report describes how a clinic run went once every patient has left.
+ */
+type Report struct {
+	// The seed which replays this run
+	Seed int64
+	// Ids of treated patients, in the order they left the clinic
+	Treated []int
+	// Ids of patients who left untreated, in ascending order, and of those
+	// the ones who balked at a full waiting room or reneged on waiting
+	Untreated []int
+	Balked    []int
+	Reneged   []int
+	// Ids of booked patients who walked in too late to be seen at their
+	// slot, and of those who never showed up, in ascending order
+	Late    []int
+	NoShows []int
+	// How long the clinic was open
+	Elapsed time.Duration
+	// What each dentist did, in dentist id order
+	Dentists []DentistReport
+	// How each patient's visit went, in patient id order
+	Patients []PatientReport
+}
+
+/**
+ * DentistReport describes the work of a single dentist during a run.
+ */
+type DentistReport struct {
+	ID int
+	// Number of patients treated by this dentist
+	Treated int
+	// How many times the dentist was woken up by an arriving patient
+	WakeUps int
+	// Time spent treating patients, and asleep waiting for one
+	Busy   time.Duration
+	Asleep time.Duration
+}
+
+/**
+ * Time the clinic was open but the dentist was not treating anyone
+ */
+func (dentist DentistReport) Idle(elapsed time.Duration) time.Duration {
+	return max(elapsed-dentist.Busy, 0)
+}
+
+/**
+ * Percentage of the time the clinic was open the dentist spent treating
+ */
+func (dentist DentistReport) Utilisation(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return 100 * float64(dentist.Busy) / float64(elapsed)
+}
+
+/**
+ * Patients treated per minute the clinic was open
+ */
+func (dentist DentistReport) Throughput(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(dentist.Treated) / elapsed.Minutes()
+}
+
+/**
+ * Runs a clinic scenario until every patient has left, either treated or
+ * sent home because ctx was cancelled. The staff is dismissed as soon as
+ * the last patient leaves, so a run only lasts as long as its treatments.
+ */
+func Run(ctx context.Context, config Config) (Report, error) {
+	return run(ctx, config, nil)
+}
+
+/**
+ * Runs a clinic scenario, pausing its actors at every checkpoint of the
+ * wake-up protocol when given a way to (see CheckWakeUps).
+ */
+func run(ctx context.Context, config Config, pause func(Actor, Checkpoint)) (Report, error) {
+	if err := config.validate(); err != nil {
+		return Report{}, err
+	}
+	if config.Protocol == nil {
+		config.Protocol = StandardProtocol
+	}
+	if err := config.Protocol.Validate(); err != nil {
+		return Report{}, err
+	}
+	for _, protocol := range config.Protocols {
+		if err := protocol.Validate(); err != nil {
+			return Report{}, err
+		}
+	}
+
+	arrivals := config.Arrivals
+	if arrivals == nil {
+		arrivals = FixedInterval(config.ArrivalInterval)
+	}
+	walkIns := 0
+	for _, level := range config.levels() {
+		walkIns += level.Patients
+	}
+	if replay, replayed := arrivals.(Replay); replayed && len(replay) < walkIns {
+		return Report{}, fmt.Errorf("clinic: replay records %d arrivals for %d patients", len(replay), walkIns)
+	}
+	if noShows := config.Appointments.NoShows; !(noShows >= 0 && noShows <= 1) {
+		return Report{}, fmt.Errorf("clinic: no-shows chance %g is not between 0 and 1", noShows)
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = RealClock{}
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = NewSeed()
+	}
+
+	sink := config.Sink
+	if sink == nil {
+		sink = NewConsoleSink(os.Stderr)
+	}
+
+	ctx, closeClinic := context.WithCancel(ctx)
+	defer closeClinic()
+
+	// creates a synchronous channel and the asynchronous waiting rooms
+	clinic := &practice{
+		config: config,
+		clock:  clock,
+		sink:   sink,
+		dent:   make(chan *Session),
+		wait:   make(chan *Session, config.WaitSize),
+		levels: config.levels(),
+		pause:  pause,
+	}
+	policy, aging := config.policy()
+	rooms := clinic.levels
+	if config.Scenario == DentistScenario {
+		rooms = []Level{{Name: "wait", Capacity: config.WaitSize}}
+	}
+	clinic.rooms = newWaitingRooms(rooms, policy, stream(seed, "scheduling"))
+
+	opened := clock.Now()
+	clinic.emit(Event{Kind: ClinicOpened, Actor: Actor{Role: ClinicRole}, Seed: seed})
+
+	var staff, patients sync.WaitGroup
+	hire := func(work func()) {
+		staff.Add(1)
+		go func() {
+			defer staff.Done()
+			work()
+		}()
+	}
+
+	report := Report{Seed: seed}
+	report.Dentists = make([]DentistReport, max(config.Dentists, 1))
+	for i := range report.Dentists {
+		report.Dentists[i].ID = i + 1
+	}
+
+	if config.Patience > 0 {
+		hire(func() { clinic.renege(ctx) })
+	}
+
+	switch config.Scenario {
+	case DentistScenario:
+		// every patient queues in the same waiting room, wait
+		for i := range report.Dentists {
+			hire(func() { clinic.dentist(ctx, nil, clinic.rooms, &report.Dentists[i]) })
+		}
+	case PriorityScenario:
+		if aging {
+			hire(func() { clinic.age(ctx, config.AgingLimit, Actor{Role: DentistRole}) })
+		}
+		for i := range report.Dentists {
+			hire(func() { clinic.priorityDentist(ctx, &report.Dentists[i]) })
+		}
+	case AssistantScenario:
+		// a ready signal buffer, so dentists never block on it
+		ready := make(chan bool, len(report.Dentists))
+		for i := range report.Dentists {
+			hire(func() { clinic.dentist(ctx, ready, nil, &report.Dentists[i]) })
+		}
+		assistants := max(config.Assistants, 1)
+		if aging {
+			hire(func() { clinic.age(ctx, config.AgingLimit, Actor{Role: AssistantRole}) })
+		}
+		for i := range assistants {
+			// a lone assistant goes without a number
+			me := Actor{Role: AssistantRole}
+			if assistants > 1 {
+				me.ID = i + 1
+			}
+			hire(func() { clinic.assistant(ctx, me) })
+		}
+		for range report.Dentists {
+			Accept(<-ready, Signal, DentistIsNotReady)
+		}
+	}
+
+	treatments := stream(seed, "treatments")
+	durations := stream(seed, "durations")
+
+	var mutex sync.Mutex
+	attend := func(session *Session) {
+		visit := clinic.patient(ctx, session)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		id := session.Patient
+		report.Patients = append(report.Patients, visit)
+		if visit.Treated {
+			report.Treated = append(report.Treated, id)
+		} else {
+			report.Untreated = append(report.Untreated, id)
+		}
+		if visit.Balked {
+			report.Balked = append(report.Balked, id)
+		}
+		if visit.Reneged {
+			report.Reneged = append(report.Reneged, id)
+		}
+		if visit.Late {
+			report.Late = append(report.Late, id)
+		}
+	}
+	admit := func(id int, priority Priority) {
+		// Creates an appointed treatment session
+		treatment := config.Treatments.Draw(treatments)
+		session := NewSession(id, config.protocol(treatment))
+		session.Priority = priority
+		session.Treatment = treatment
+		session.Duration = config.Durations.For(session.Treatment).Duration(durations)
+
+		patients.Add(1)
+		go func() {
+			defer patients.Done()
+			attend(session)
+		}()
+	}
+
+	gaps := stream(seed, "arrivals")
+
+	// Patients are numbered from the least urgent level up,
+	// but the most urgent ones walk in first
+	first := make([]int, len(clinic.levels))
+	for level := 1; level < len(first); level++ {
+		first[level] = first[level-1] + clinic.levels[level-1].Patients
+	}
+
+	// Booked patients are numbered after the walk-ins, and come in on their own
+	booked := stream(seed, "appointments")
+	for i, appointment := range config.Appointments.Booked {
+		// Creates the booked treatment session, from a stream of its own
+		// so bookings never shift the walk-ins' draws
+		treatment := appointment.Treatment
+		if treatment == "" {
+			treatment = config.Treatments.Draw(booked)
+		}
+		session := NewSession(walkIns+i+1, config.protocol(treatment))
+		session.Priority = min(max(appointment.Priority, 0), clinic.top())
+		session.Treatment = treatment
+		session.Duration = config.Durations.For(session.Treatment).Duration(booked)
+		session.Slot = opened.Add(appointment.Slot)
+		turnsUp, shows := config.Appointments.turnUp(appointment, booked)
+
+		patients.Add(1)
+		go func() {
+			defer patients.Done()
+			if !shows {
+				// The clinic gives up on them once their slot is past the tolerance
+				arrive(ctx, clock, session.Slot.Add(config.Appointments.Tolerance).Sub(clock.Now()))
+				clinic.emit(Event{Kind: AppointmentMissed, Actor: Actor{Role: PatientRole, ID: session.Patient}, Patient: session.Patient, Priority: session.Priority})
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				report.NoShows = append(report.NoShows, session.Patient)
+				return
+			}
+			arrive(ctx, clock, opened.Add(turnsUp).Sub(clock.Now()))
+			attend(session)
+		}()
+	}
+
+	// A replay tells when its first patient arrives, from the opening
+	opening := config.Opening
+	if process, recorded := arrivals.(FirstArrival); recorded {
+		opening = process.First()
+	}
+	arrive(ctx, clock, opening)
+	n := 0
+	for level := clinic.top(); level >= 0; level-- {
+		for i := 1; i <= clinic.levels[level].Patients; i++ {
+			if n > 0 {
+				arrive(ctx, clock, arrivals.Gap(n, gaps))
+			}
+			admit(first[level]+i, level)
+			n++
+		}
+	}
+
+	// The clinic closes as soon as the last patient leaves
+	patients.Wait()
+	closeClinic()
+	staff.Wait()
+
+	// Nobody moves patients around once the staff is gone
+	Dismiss(clinic.wait)
+	clinic.rooms.dismiss()
+
+	sort.Ints(report.Untreated)
+	sort.Ints(report.Balked)
+	sort.Ints(report.Reneged)
+	sort.Ints(report.Late)
+	sort.Ints(report.NoShows)
+	sort.Slice(report.Patients, func(i, j int) bool { return report.Patients[i].ID < report.Patients[j].ID })
+	report.Elapsed = clock.Now().Sub(opened)
+	for i := range report.Dentists {
+		dentist := report.Dentists[i]
+		clinic.emit(Event{Kind: DentistFinished, Actor: Actor{Role: DentistRole, ID: dentist.ID}, Duration: report.Elapsed, Dentist: &dentist})
+	}
+	clinic.emit(Event{Kind: ClinicClosed, Actor: Actor{Role: ClinicRole}, Seed: seed})
+
+	return report, nil
+}
+
+/**
+ * Checks the staff, waiting rooms and patients of a run add up to a
+ * clinic. Every room a scenario seats patients in has at least one seat,
+ * but the dentist's wait of part 3 may have none, the assistant then
+ * handing patients to the dentist directly.
+ */
+func (config Config) validate() error {
+	if config.Dentists < 0 || config.Assistants < 0 {
+		return fmt.Errorf("clinic: %d dentists and %d assistants cannot open a clinic", config.Dentists, config.Assistants)
+	}
+	if config.WaitSize < 0 || config.WaitSize == 0 && config.Scenario == DentistScenario {
+		return fmt.Errorf("clinic: waiting room wait cannot seat %d patients", config.WaitSize)
+	}
+	for _, level := range config.levels() {
+		if level.Capacity < 1 && config.Scenario != DentistScenario {
+			return fmt.Errorf("clinic: waiting room %s cannot seat %d patients", level.Name, level.Capacity)
+		}
+		if level.Patients < 0 {
+			return fmt.Errorf("clinic: %d %s priority patients cannot walk in", level.Patients, level.Name)
+		}
+	}
+	return nil
+}
+
+/**
+ * A practice is the clinic during a single run: its settings, its
+ * clock, where its events go, and the channels its actors share.
+ */
+type practice struct {
+	config Config
+	clock  Clock
+	sink   Sink
+
+	dent chan *Session
+	// Where the assistant places patients for the dentist (part 3)
+	wait chan *Session
+	// The priority levels, the least urgent first, and the waiting rooms
+	// patients queue in (a single one, wait, for every level of part 1)
+	levels []Level
+	rooms  *waitingRooms
+
+	// Pauses an actor at a checkpoint, nil outside of CheckWakeUps
+	pause func(Actor, Checkpoint)
+}
+
+/**
+ * Publishes an event, stamped with the time and the waiting rooms' depths
+ */
+func (clinic *practice) emit(event Event) {
+	event.Time = clinic.clock.Now()
+	if event.Patient != 0 {
+		event.Level = clinic.level(event.Priority)
+	}
+
+	lengths := clinic.rooms.lengths()
+	// The dentist scenario only has the one waiting room, wait
+	if clinic.config.Scenario == DentistScenario {
+		event.Queues.Wait = lengths[0]
+	} else {
+		event.Queues.Wait = len(clinic.wait)
+		event.Queues.Levels = lengths
+		event.Queues.HWait = lengths[clinic.top()]
+		event.Queues.LWait = lengths[0]
+	}
+	clinic.sink.Publish(event)
+}
+
+/**
+ * Waits for the next patient to arrive, unless the clinic closes first.
+ */
+func arrive(ctx context.Context, clock Clock, after time.Duration) {
+	if after <= 0 {
+		return
+	}
+
+	timer := clock.NewTimer(after)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,40 @@
+package clinic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunRejectsAClinicThatDoesNotAddUp(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config Config
+		fails  bool
+	}{
+		{"no dentist", Config{Scenario: DentistScenario, Dentists: -1, WaitSize: 1}, true},
+		{"no assistant", Config{Scenario: AssistantScenario, Assistants: -1, HWaitSize: 1, LWaitSize: 1}, true},
+		{"negative wait", Config{Scenario: AssistantScenario, WaitSize: -1, HWaitSize: 1, LWaitSize: 1}, true},
+		{"seatless wait", Config{Scenario: DentistScenario}, true},
+		{"seatless hwait", Config{Scenario: PriorityScenario, LWaitSize: 1}, true},
+		{"seatless level", Config{Scenario: AssistantScenario, Levels: []Level{{Name: "routine", Capacity: 1}, {Name: "urgent"}}}, true},
+		{"negative patients", Config{Scenario: DentistScenario, WaitSize: 1, LowPatients: -1}, true},
+		{"negative level patients", Config{Scenario: PriorityScenario, Levels: []Level{{Name: "routine", Capacity: 1, Patients: -2}}}, true},
+		// The assistant hands patients over to the dentist, and nobody seats in hwait and lwait in part 1
+		{"seatless wait of part 3", Config{Scenario: AssistantScenario, HWaitSize: 1, LWaitSize: 1, LowPatients: 2}, false},
+		{"seatless rooms of part 1", Config{Scenario: DentistScenario, WaitSize: 1, LowPatients: 2}, false},
+	} {
+		test.config.Clock = NewVirtualClock(time.Unix(0, 0))
+		test.config.Seed = 1
+		test.config.Sink = Sinks{}
+		report, err := Run(context.Background(), test.config)
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("%s: ran, want an error", test.name)
+		case !test.fails && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case !test.fails && len(report.Treated) != 2:
+			t.Errorf("%s: treated %v, want both patients", test.name, report.Treated)
+		}
+	}
+}
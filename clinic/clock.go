@@ -0,0 +1,302 @@
+package clinic
+
+import (
+	"bytes"
+	"container/heap"
+	"runtime"
+	"sync"
+	"time"
+)
+
+/** clocks **********************************************************/
+
+/**
+ * A clock tells the time and puts goroutines to sleep. Every timing path
+ * of a clinic goes through one, so a run can use real or simulated time.
+ */
+type Clock interface {
+	Now() time.Time
+	Sleep(duration time.Duration)
+	NewTimer(duration time.Duration) Timer
+}
+
+/**
+ * A timer sends the current time on C once its duration has passed.
+ */
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(duration time.Duration) bool
+}
+
+/** real clock **********************************************************/
+
+/**
+ * The wall clock, as told by the time package.
+ */
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+func (RealClock) NewTimer(duration time.Duration) Timer {
+	return realTimer{time.NewTimer(duration)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (timer realTimer) C() <-chan time.Time {
+	return timer.Timer.C
+}
+
+/** virtual clock **********************************************************/
+
+/**
+ * A simulated clock which only moves forward once every goroutine of the
+ * program is blocked (on a channel, a lock or one of the clock's timers),
+ * and then jumps straight to the next timer due. Sleeping costs no real
+ * time, so a run takes as long as its goroutines need to compute, and
+ * the same run always sees the same times.
+ *
+ * Goroutines are told apart as blocked or not by their scheduling state,
+ * so nothing else may be running in the program while the clock is in use
+ * (other than goroutines waiting on I/O, which count as blocked).
+ */
+type VirtualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	timers  virtualTimers
+	counter uint64
+	driving bool
+
+	// Where the driving goroutine reads the program's stacks into
+	stacks []byte
+}
+
+/**
+ * Creates a virtual clock reading start.
+ */
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (clock *VirtualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *VirtualClock) Sleep(duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	<-clock.NewTimer(duration).C()
+}
+
+func (clock *VirtualClock) NewTimer(duration time.Duration) Timer {
+	timer := &virtualTimer{clock: clock, c: make(chan time.Time, 1), index: -1}
+	timer.Reset(duration)
+	return timer
+}
+
+/**
+ * Schedules a timer, and starts driving the clock if nobody else is.
+ * Must be called with the mutex held.
+ */
+func (clock *VirtualClock) schedule(timer *virtualTimer) {
+	clock.counter++
+	timer.order = clock.counter
+	heap.Push(&clock.timers, timer)
+
+	if !clock.driving {
+		clock.driving = true
+		go clock.drive()
+	}
+}
+
+/**
+ * Moves the clock to the next timer due whenever the program is blocked,
+ * until no timer is left.
+ */
+func (clock *VirtualClock) drive() {
+	attempts := 0
+	for clock.pending() {
+		if !clock.blocked() {
+			// Let the other goroutines run, backing off while they are busy
+			attempts++
+			if attempts < 16 {
+				runtime.Gosched()
+			} else {
+				time.Sleep(min(time.Duration(attempts)*time.Microsecond, time.Millisecond))
+			}
+			continue
+		}
+		attempts = 0
+
+		clock.mutex.Lock()
+		if len(clock.timers) > 0 {
+			next := heap.Pop(&clock.timers).(*virtualTimer)
+			if next.deadline.After(clock.now) {
+				clock.now = next.deadline
+			}
+			next.fire(clock.now)
+		}
+		clock.mutex.Unlock()
+	}
+}
+
+/**
+ * Reports whether any timer is left, and stops driving the clock otherwise.
+ */
+func (clock *VirtualClock) pending() bool {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.driving = len(clock.timers) > 0
+	return clock.driving
+}
+
+/**
+ * Reports whether every other goroutine of the program is blocked.
+ */
+func (clock *VirtualClock) blocked() bool {
+	if clock.stacks == nil {
+		clock.stacks = make([]byte, 64<<10)
+	}
+	n := runtime.Stack(clock.stacks, true)
+	for n == len(clock.stacks) {
+		clock.stacks = make([]byte, 2*len(clock.stacks))
+		n = runtime.Stack(clock.stacks, true)
+	}
+	buffer := clock.stacks[:n]
+
+	// The first goroutine is always the one asking
+	goroutines := bytes.Split(buffer, []byte("\n\ngoroutine "))
+	for _, goroutine := range goroutines[1:] {
+		if !waiting(goroutine) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * Reads the state of a goroutine off its stack trace header,
+ * e.g. "18 [chan receive, 2 minutes]:".
+ */
+func waiting(goroutine []byte) bool {
+	open := bytes.IndexByte(goroutine, '[')
+	end := bytes.IndexAny(goroutine, ",]")
+	if open < 0 || end < open {
+		return false
+	}
+
+	switch string(goroutine[open+1 : end]) {
+	case "running", "runnable", "preempted", "copystack":
+		return false
+	case "syscall":
+		// The signal (and profile) readers spend their life in a system call
+		return bytes.Contains(goroutine, []byte("os/signal.signal_recv")) ||
+			bytes.Contains(goroutine, []byte("runtime/pprof.readProfile"))
+	default:
+		return true
+	}
+}
+
+/** virtual timers **********************************************************/
+
+type virtualTimer struct {
+	clock    *VirtualClock
+	c        chan time.Time
+	deadline time.Time
+	order    uint64
+	index    int
+}
+
+func (timer *virtualTimer) C() <-chan time.Time {
+	return timer.c
+}
+
+func (timer *virtualTimer) Stop() bool {
+	timer.clock.mutex.Lock()
+	defer timer.clock.mutex.Unlock()
+	return timer.stop()
+}
+
+func (timer *virtualTimer) Reset(duration time.Duration) bool {
+	timer.clock.mutex.Lock()
+	defer timer.clock.mutex.Unlock()
+
+	active := timer.stop()
+	timer.deadline = timer.clock.now.Add(duration)
+	timer.clock.schedule(timer)
+	return active
+}
+
+/**
+ * Like time.Timer, a stopped timer never delivers a stale time afterwards.
+ * Must be called with the clock's mutex held.
+ */
+func (timer *virtualTimer) stop() bool {
+	select {
+	case <-timer.c:
+	default:
+	}
+
+	if timer.index < 0 {
+		return false
+	}
+	heap.Remove(&timer.clock.timers, timer.index)
+	return true
+}
+
+func (timer *virtualTimer) fire(now time.Time) {
+	select {
+	case timer.c <- now:
+	default:
+	}
+}
+
+/**
+ * Pending timers, the earliest due first (and the earliest scheduled
+ * first among timers due at the same time).
+ */
+type virtualTimers []*virtualTimer
+
+func (timers virtualTimers) Len() int {
+	return len(timers)
+}
+
+func (timers virtualTimers) Less(i, j int) bool {
+	if timers[i].deadline.Equal(timers[j].deadline) {
+		return timers[i].order < timers[j].order
+	}
+	return timers[i].deadline.Before(timers[j].deadline)
+}
+
+func (timers virtualTimers) Swap(i, j int) {
+	timers[i], timers[j] = timers[j], timers[i]
+	timers[i].index = i
+	timers[j].index = j
+}
+
+func (timers *virtualTimers) Push(timer any) {
+	timer.(*virtualTimer).index = len(*timers)
+	*timers = append(*timers, timer.(*virtualTimer))
+}
+
+func (timers *virtualTimers) Pop() any {
+	old := *timers
+	timer := old[len(old)-1]
+	old[len(old)-1] = nil
+	timer.index = -1
+	*timers = old[:len(old)-1]
+	return timer
+}
@@ -0,0 +1,109 @@
+package clinic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVirtualClockRunsAScenarioInstantly(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		t.Run(scenario.String(), func(t *testing.T) {
+			config := Config{
+				Scenario:     scenario,
+				WaitSize:     15,
+				HWaitSize:    20,
+				LWaitSize:    10,
+				AgingLimit:   500 * time.Millisecond,
+				LowPatients:  10,
+				HighPatients: 20,
+				Opening:      2 * time.Second,
+				Clock:        NewVirtualClock(time.Unix(0, 0)),
+				Seed:         42,
+				Sink:         Sinks{},
+			}
+
+			began := time.Now()
+			report, err := Run(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			took := time.Since(began)
+
+			if len(report.Treated) != 30 || len(report.Untreated) != 0 {
+				t.Fatalf("treated %v and not %v, want all 30 patients treated", report.Treated, report.Untreated)
+			}
+
+			// The lone dentist is never idle once everyone walked in at the opening
+			chair := time.Duration(0)
+			for _, patient := range report.Patients {
+				chair += patient.InTreatment()
+			}
+			if report.Elapsed != config.Opening+chair {
+				t.Errorf("clinic was open %v, want the opening and every treatment, %v", report.Elapsed, config.Opening+chair)
+			}
+			// The same seed always sees the same times
+			if want := time.Minute + 1335114551*time.Nanosecond; report.Elapsed != want {
+				t.Errorf("clinic was open %v, want %v with seed 42", report.Elapsed, want)
+			}
+			if took > 5*time.Second {
+				t.Errorf("a virtual minute took %v, want it to go as fast as the goroutines can", took)
+			}
+		})
+	}
+}
+
+func TestVirtualClockFiresTimersInOrder(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewVirtualClock(start)
+
+	late := clock.NewTimer(2 * time.Second)
+	early := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(500 * time.Millisecond)
+	if !stopped.Stop() {
+		t.Error("stopping a pending timer reported it already fired")
+	}
+
+	if fired := <-early.C(); !fired.Equal(start.Add(time.Second)) {
+		t.Errorf("early timer fired at %v, want %v", fired, start.Add(time.Second))
+	}
+	if fired := <-late.C(); !fired.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("late timer fired at %v, want %v", fired, start.Add(2*time.Second))
+	}
+	select {
+	case <-stopped.C():
+		t.Error("a stopped timer fired")
+	default:
+	}
+
+	clock.Sleep(time.Hour)
+	if now := clock.Now(); !now.Equal(start.Add(time.Hour + 2*time.Second)) {
+		t.Errorf("clock reads %v after sleeping, want %v", now, start.Add(time.Hour+2*time.Second))
+	}
+}
+
+func TestGoroutineStates(t *testing.T) {
+	for _, test := range []struct {
+		header  string
+		waiting bool
+	}{
+		{"18 [chan receive]:\nmain.main()", true},
+		{"18 [chan receive, 2 minutes]:\nmain.main()", true},
+		{"18 [chan send (nil chan)]:\nmain.main()", true},
+		{"18 [select]:\nmain.main()", true},
+		{"18 [select (no cases)]:\nmain.main()", true},
+		{"18 [sync.Mutex.Lock]:\nmain.main()", true},
+		{"18 [sync.WaitGroup.Wait]:\nmain.main()", true},
+		{"18 [running]:\nmain.main()", false},
+		{"18 [runnable]:\nmain.main()", false},
+		{"18 [runnable, locked to thread]:\nmain.main()", false},
+		{"18 [preempted]:\nmain.main()", false},
+		{"18 [syscall]:\nsyscall.read()", false},
+		{"18 [syscall, 3 minutes]:\nos/signal.signal_recv()", true},
+		{"18 garbled", false},
+	} {
+		if waiting := waiting([]byte(test.header)); waiting != test.waiting {
+			t.Errorf("goroutine %q is waiting: %t, want %t", test.header, waiting, test.waiting)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package clinic
+
+import "runtime"
+
+/** colors **********************************************************/
+
+/**
+ * Colors used to make logs more readable
+ */
+var clear = color("\033[0m")
+var red = color("\033[31m")
+var green = color("\033[32m")
+var yellow = color("\033[33m")
+var blue = color("\033[34m")
+var cyan = color("\033[36m")
+var purple = color("\033[35m")
+var gray = color("\033[37m")
+
+/**
+ * Windows consoles do not render ANSI escape codes, so colors are dropped
+ * there. This is resolved during variable initialisation (rather than in
+ * an init function) so the events below are built with the right codes.
+ */
+func color(code string) string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	return code
+}
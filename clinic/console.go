@@ -0,0 +1,120 @@
+package clinic
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+/** console **********************************************************/
+
+/**
+ * A sink printing events as the colour-coded lines the clinic always
+ * logged, stamped with the time of the run (virtual or not).
+ */
+type ConsoleSink struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+/**
+ * Creates a console sink printing to out.
+ */
+func NewConsoleSink(out io.Writer) *ConsoleSink {
+	return &ConsoleSink{out: out}
+}
+
+func (console *ConsoleSink) Publish(event Event) {
+	format, details, printed := consoleFormat(event)
+	if !printed {
+		return
+	}
+
+	name := event.Actor.String()
+	if event.Kind == ClinicOpened {
+		name = fmt.Sprintf("%s (seed %d)", name, event.Seed)
+	}
+
+	line := format
+	if strings.Contains(format, "%s") {
+		line = fmt.Sprintf(format, append([]any{name}, details...)...)
+	}
+
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+	fmt.Fprintf(console.out, "%s %s\n", event.Time.Format("15:04:05"), line)
+}
+
+/**
+ * The log line of an event, if it has one, and the details
+ * it shows besides the name of whoever is acting
+ */
+func consoleFormat(event Event) (string, []any, bool) {
+	format, printed := consoleLine(event)
+	switch event.Kind {
+	case PatientFound, PatientAged:
+		return format, []any{event.Level}, printed
+	case PatientPlaced:
+		return format, []any{strings.ToUpper(event.Level)}, printed
+	default:
+		return format, nil, printed
+	}
+}
+
+func consoleLine(event Event) (string, bool) {
+	switch event.Kind {
+	case ClinicOpened:
+		return ClinicIsOpen, true
+	case FellAsleep:
+		return WentToSleep, true
+	case WokeUp:
+		return WakesUp, true
+	case Closing:
+		return ClosingTheClinic, true
+	case PatientArrived:
+		return RequestTreatment, true
+	case PatientWokeUp:
+		return DentistNotBusy, true
+	case PatientQueued:
+		return WaitingForTreatment, true
+	case PatientLeft:
+		return LeaveClinic, true
+	case PatientSentHome:
+		return SentHome, true
+	case PatientBalked:
+		return Balked, true
+	case PatientReneged:
+		return Reneged, true
+	case PatientLate:
+		return LateForAppointment, true
+	case AppointmentMissed:
+		return MissedAppointment, true
+	case PatientFound:
+		if event.Priority == LowPriority {
+			return FoundALessUrgentPatient, true
+		}
+		return FoundAPriorityPatient, true
+	case PatientPlaced:
+		return PlacingAPriorityPatient, true
+	case PatientAged:
+		return MovingAPatientUp, true
+	case StepPerformed:
+		format, logged := dentistSteps[event.Step]
+		return format, logged
+	case StepReceived:
+		format, logged := patientSteps[event.Step]
+		return format, logged
+	case ProtocolFailed:
+		switch {
+		case event.Actor.Role == DentistRole:
+			return GetOffTheChair, true
+		case event.Step == Finish:
+			return TreatmentIsComplete, true
+		default:
+			return TreatmentMustBeInSync, true
+		}
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,305 @@
+package clinic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** dashboard **********************************************************/
+
+/**
+ * Escape codes driving the terminal the dashboard is drawn in
+ */
+const (
+	enterScreen = "\033[?1049h\033[?25l"
+	leaveScreen = "\033[?25h\033[?1049l"
+	redraw      = "\033[H\033[2J"
+)
+
+/**
+ * How wide the queue bars and the throughput graph are drawn
+ */
+const (
+	barWidth     = 40
+	graphSeconds = 60
+)
+
+var graphLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+/**
+ * A sink drawing a live, full screen view of a run: how full each waiting
+ * room is, what each dentist is doing, when the next patient moves a priority
+ * level up, and how many patients left treated over the last minute.
+ */
+type Dashboard struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	config Config
+	clock  Clock
+
+	seed     int64
+	opened   time.Time
+	now      time.Time
+	queues   QueueDepths
+	dentists map[int]dentistView
+	treated  int
+	sentHome int
+	balked   int
+	reneged  int
+	late     int
+	noShows  int
+	// The patients waiting below the top level, and since when
+	waiting map[int]waitingView
+	// The patients a dentist or an assistant took out of the waiting rooms
+	taken map[int]bool
+	// Patients treated during each second since the clinic opened
+	throughput []int
+}
+
+/**
+ * What a dentist is doing, as shown on the dashboard
+ */
+type dentistView struct {
+	patient int
+	phase   string
+}
+
+/**
+ * Where a patient waits, as far as the dashboard can tell
+ */
+type waitingView struct {
+	priority Priority
+	since    time.Time
+}
+
+/**
+ * Creates a dashboard for a run of config, drawn to out.
+ */
+func NewDashboard(out io.Writer, config Config) *Dashboard {
+	clock := config.Clock
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &Dashboard{out: out, config: config, clock: clock, dentists: map[int]dentistView{}, waiting: map[int]waitingView{}, taken: map[int]bool{}}
+}
+
+func (dashboard *Dashboard) Publish(event Event) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	dashboard.now = event.Time
+	dashboard.queues = event.Queues
+
+	switch event.Kind {
+	case ClinicOpened:
+		dashboard.seed = event.Seed
+		dashboard.opened = event.Time
+	case FellAsleep:
+		dashboard.dentists[event.Actor.ID] = dentistView{phase: "asleep"}
+	case WokeUp:
+		dashboard.dentists[event.Actor.ID] = dentistView{patient: event.Patient, phase: "woke up"}
+	case StepPerformed:
+		dashboard.dentists[event.Actor.ID] = dentistView{patient: event.Patient, phase: event.Step.String()}
+	case TreatmentDone:
+		dashboard.dentists[event.Actor.ID] = dentistView{phase: "idle"}
+	case Closing:
+		if event.Actor.Role == DentistRole {
+			dashboard.dentists[event.Actor.ID] = dentistView{phase: "closed"}
+		}
+	case PatientQueued, PatientAged:
+		// Every patient ages on their own wait, which starts over a level up.
+		// A patient may tell they sat down after they were already taken.
+		if dashboard.config.Scenario != DentistScenario && !dashboard.taken[event.Patient] {
+			dashboard.waiting[event.Patient] = waitingView{priority: event.Priority, since: event.Time}
+		}
+	case PatientFound, PatientPlaced:
+		delete(dashboard.waiting, event.Patient)
+		dashboard.taken[event.Patient] = true
+	case PatientLeft:
+		dashboard.treated++
+		dashboard.throughput[dashboard.second(event.Time)]++
+	case PatientSentHome:
+		delete(dashboard.waiting, event.Patient)
+		dashboard.sentHome++
+	case PatientBalked:
+		dashboard.balked++
+	case PatientReneged:
+		delete(dashboard.waiting, event.Patient)
+		dashboard.reneged++
+	case PatientLate:
+		dashboard.late++
+	case AppointmentMissed:
+		dashboard.noShows++
+	}
+}
+
+/**
+ * Draws the dashboard every interval until ctx is done, then draws it
+ * one last time and gives the terminal back.
+ */
+func (dashboard *Dashboard) Show(ctx context.Context, interval time.Duration) {
+	io.WriteString(dashboard.out, enterScreen)
+	defer io.WriteString(dashboard.out, leaveScreen)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		dashboard.draw()
+		select {
+		case <-ctx.Done():
+			dashboard.draw()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (dashboard *Dashboard) draw() {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	var screen strings.Builder
+	screen.WriteString(redraw)
+
+	now := dashboard.now
+	if clock := dashboard.clock.Now(); clock.After(now) {
+		now = clock
+	}
+	open := time.Duration(0)
+	if !dashboard.opened.IsZero() {
+		open = now.Sub(dashboard.opened).Round(time.Second)
+		// The graph goes on while nobody leaves
+		dashboard.second(now)
+	}
+
+	fmt.Fprintf(&screen, "%sClinic (seed %d)%s  open %v  treated %d  sent home %d  balked %d  reneged %d\n\n",
+		gray, dashboard.seed, clear, open, dashboard.treated, dashboard.sentHome, dashboard.balked, dashboard.reneged)
+	if len(dashboard.config.Appointments.Booked) > 0 {
+		fmt.Fprintf(&screen, "%sAppointments%s  booked %d  late %d  no-shows %d\n\n",
+			gray, clear, len(dashboard.config.Appointments.Booked), dashboard.late, dashboard.noShows)
+	}
+
+	// Waiting rooms
+	config := dashboard.config
+	fmt.Fprintf(&screen, "%sWaiting rooms%s\n", blue, clear)
+	if config.Scenario != PriorityScenario {
+		bar(&screen, "wait", dashboard.queues.Wait, config.WaitSize, green)
+	}
+	if config.Scenario != DentistScenario {
+		// the most urgent level first, the two levels of parts 2 and 3 as hwait and lwait
+		levels := config.levels()
+		for priority := len(levels) - 1; priority >= 0; priority-- {
+			room, color := levels[priority].Name, cyan
+			switch priority {
+			case len(levels) - 1:
+				color = red
+				if len(config.Levels) == 0 {
+					room = "hwait"
+				}
+			case 0:
+				color = yellow
+				if len(config.Levels) == 0 {
+					room = "lwait"
+				}
+			}
+			length := 0
+			if priority < len(dashboard.queues.Levels) {
+				length = dashboard.queues.Levels[priority]
+			}
+			bar(&screen, room, length, levels[priority].Capacity, color)
+		}
+	}
+
+	// Aging countdown
+	if _, aging := config.policy(); aging && config.Scenario != DentistScenario && config.AgingLimit > 0 {
+		fmt.Fprintf(&screen, "\n%sAging%s  %s\n", cyan, clear, dashboard.nextAging(now))
+	}
+
+	// Dentists
+	fmt.Fprintf(&screen, "\n%sDentists%s\n", blue, clear)
+	ids := make([]int, 0, len(dashboard.dentists))
+	for id := range dashboard.dentists {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		view := dashboard.dentists[id]
+		if view.patient == 0 {
+			fmt.Fprintf(&screen, "  Dentist (%d)  %s\n", id, view.phase)
+		} else {
+			fmt.Fprintf(&screen, "  Dentist (%d)  %-8s Patient (%d)\n", id, view.phase, view.patient)
+		}
+	}
+
+	// Throughput over the last minute
+	fmt.Fprintf(&screen, "\n%sTreated per second%s (last %ds)\n  %s\n", blue, clear, graphSeconds, graph(dashboard.throughput))
+
+	io.WriteString(dashboard.out, screen.String())
+}
+
+/**
+ * The second since the opening at, counting patients treated up to it
+ */
+func (dashboard *Dashboard) second(at time.Time) int {
+	second := int(at.Sub(dashboard.opened) / time.Second)
+	for len(dashboard.throughput) <= second {
+		dashboard.throughput = append(dashboard.throughput, 0)
+	}
+	return second
+}
+
+/**
+ * When the next patient waiting below the top level moves a level up
+ */
+func (dashboard *Dashboard) nextAging(now time.Time) string {
+	top := Priority(len(dashboard.config.levels()) - 1)
+	var next time.Time
+	for _, waiting := range dashboard.waiting {
+		if waiting.priority >= top {
+			continue
+		}
+		if due := waiting.since.Add(dashboard.config.AgingLimit); next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	if next.IsZero() {
+		return "nobody is waiting to move a level up"
+	}
+	remaining := max(next.Sub(now), 0)
+	return fmt.Sprintf("next patient moves a level up in %v", remaining.Round(10*time.Millisecond))
+}
+
+/**
+ * Draws how full a waiting room is
+ */
+func bar(screen *strings.Builder, room string, length int, capacity int, color string) {
+	filled := min(length, barWidth)
+	if capacity > 0 {
+		filled = min(length*barWidth/capacity, barWidth)
+	}
+	fmt.Fprintf(screen, "  %-10s %s%s%s%s %d/%d\n",
+		room, color, strings.Repeat("█", filled), clear, strings.Repeat("·", barWidth-filled), length, capacity)
+}
+
+/**
+ * Draws the last graphSeconds of throughput, scaled to the busiest second
+ */
+func graph(throughput []int) string {
+	recent := throughput[max(len(throughput)-graphSeconds, 0):]
+	busiest := 1
+	for _, treated := range recent {
+		busiest = max(busiest, treated)
+	}
+
+	var line strings.Builder
+	for _, treated := range recent {
+		line.WriteRune(graphLevels[treated*(len(graphLevels)-1)/busiest])
+	}
+	return line.String()
+}
@@ -0,0 +1,53 @@
+package clinic
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardIgnoresPatientsQueuingOnceTaken(t *testing.T) {
+	opened := time.Unix(0, 0)
+	config := Config{Scenario: AssistantScenario, AgingLimit: time.Second, Clock: NewVirtualClock(opened)}
+	dashboard := NewDashboard(&strings.Builder{}, config)
+	assistant := Actor{Role: AssistantRole, ID: 1}
+
+	dashboard.Publish(Event{Kind: ClinicOpened, Time: opened})
+	// The assistant places the patient before they told they sat down
+	dashboard.Publish(Event{Kind: PatientPlaced, Actor: assistant, Patient: 1, Time: opened})
+	dashboard.Publish(Event{Kind: PatientQueued, Patient: 1, Priority: 0, Time: opened})
+
+	if aging := dashboard.nextAging(opened.Add(2 * time.Second)); aging != "nobody is waiting to move a level up" {
+		t.Errorf("a placed patient still shows: %s", aging)
+	}
+
+	dashboard.Publish(Event{Kind: PatientQueued, Patient: 2, Priority: 0, Time: opened})
+	if aging := dashboard.nextAging(opened.Add(400 * time.Millisecond)); aging != "next patient moves a level up in 600ms" {
+		t.Errorf("a waiting patient shows as: %s", aging)
+	}
+}
+
+func TestDashboardThroughputGoesOnWhileNobodyLeaves(t *testing.T) {
+	opened := time.Unix(0, 0)
+	clock := NewVirtualClock(opened)
+	out := &strings.Builder{}
+	dashboard := NewDashboard(out, Config{Scenario: DentistScenario, Clock: clock})
+
+	dashboard.Publish(Event{Kind: ClinicOpened, Time: opened})
+	dashboard.Publish(Event{Kind: PatientLeft, Patient: 1, Time: opened.Add(1500 * time.Millisecond)})
+	clock.Sleep(5 * time.Second)
+	dashboard.draw()
+
+	if want := []int{0, 1, 0, 0, 0, 0}; !slices.Equal(dashboard.throughput, want) {
+		t.Errorf("throughput %v 5s after the opening, want %v", dashboard.throughput, want)
+	}
+	if !strings.Contains(out.String(), graph([]int{0, 1, 0, 0, 0, 0})) {
+		t.Errorf("the graph stops at the last patient leaving:\n%s", out)
+	}
+
+	dashboard.Publish(Event{Kind: PatientLeft, Patient: 2, Time: opened.Add(5 * time.Second)})
+	if want := []int{0, 1, 0, 0, 0, 1}; !slices.Equal(dashboard.throughput, want) {
+		t.Errorf("throughput %v, want %v", dashboard.throughput, want)
+	}
+}
@@ -0,0 +1,247 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+/** dentist **********************************************************/
+
+/**
+ * The dentist of parts 1 and 3. The dentist checks for patients in the
+ * waiting room (rooms in part 1, wait where the assistant places them in part 3).
+ *   • If there are no patients, the dentist falls asleep.
+ *   • If there are is at least one patient, the dentist calls the first one in.
+ *     The remaining patients keep waiting. During the treatment, the dentist is
+ *     active while the patient is sleeping. When the dentist finishes the treatment,
+ *     the patient is woken up, and the dentist checks for patients in the waiting room.
+ *     And so on...
+ *
+ * Many dentists can share the same waiting room. Asleep, they all wait on dent,
+ * a synchronous channel, so an arriving patient wakes up exactly one of them,
+ * and on the waiting room itself (see sleep).
+ *
+ * When given a ready channel, the dentist signals it the first time they fall
+ * asleep. It must be buffered for every dentist, as no signal is awaited twice.
+ */
+func (clinic *practice) dentist(ctx context.Context, ready chan<- bool, rooms *waitingRooms, record *DentistReport) {
+	me := Actor{Role: DentistRole, ID: record.ID}
+	for {
+		select {
+		case <-ctx.Done():
+			clinic.emit(Event{Kind: Closing, Actor: me})
+			return
+		default:
+		}
+
+		if nextPatient, found := clinic.call(me, rooms); found {
+			clinic.treat(ctx, nextPatient, record)
+			continue
+		}
+
+		// Sleep when no patients found in the waiting room
+		clinic.checkpoint(me, FoundNoPatient)
+		clinic.emit(Event{Kind: FellAsleep, Actor: me})
+		if ready != nil {
+			ready <- Signal
+			ready = nil
+		}
+		// But wake up when a patient shows up and requests a treatment
+		clinic.sleep(ctx, record, rooms)
+	}
+}
+
+/**
+ * Calls the next patient in, if anyone is waiting: out of rooms
+ * when given, out of wait otherwise (the booked patients due first).
+ */
+func (clinic *practice) call(me Actor, rooms *waitingRooms) (*Session, bool) {
+	if patient, found := clinic.appointment(me); found {
+		return patient, true
+	}
+	if rooms != nil {
+		patient, _, found := rooms.next(clinic.clock.Now())
+		return patient, found
+	}
+	select {
+	case patient := <-clinic.wait:
+		return patient, true
+	default:
+		return nil, false
+	}
+}
+
+/**
+ * Whether the dentist of part 3 takes the booked patients out of the
+ * priority rooms at their slot, rather than out of wait where the
+ * assistant would have placed them behind every walk-in.
+ */
+func (clinic *practice) booking() bool {
+	return clinic.config.Scenario == AssistantScenario && len(clinic.config.Appointments.Booked) > 0
+}
+
+/**
+ * Takes the booked patient whose slot came out of the priority rooms,
+ * when the dentist is booking.
+ */
+func (clinic *practice) appointment(me Actor) (*Session, bool) {
+	if !clinic.booking() {
+		return nil, false
+	}
+	patient, priority, found := clinic.rooms.appointment(clinic.clock.Now())
+	if found {
+		clinic.emit(Event{Kind: PatientFound, Actor: me, Patient: patient.Patient, Priority: priority})
+	}
+	return patient, found
+}
+
+/**
+ * The dentist of part 2. Same as the dentist, but patients are treated
+ * strictly by priority level, the most urgent first.
+ */
+func (clinic *practice) priorityDentist(ctx context.Context, record *DentistReport) {
+	me := Actor{Role: DentistRole, ID: record.ID}
+	for {
+		select {
+		case <-ctx.Done():
+			clinic.emit(Event{Kind: Closing, Actor: me})
+			return
+		default:
+		}
+
+		patient, priority, found := clinic.rooms.next(clinic.clock.Now())
+		if !found {
+			// Sleep until a patient shows up and requests a treatment
+			clinic.checkpoint(me, FoundNoPatient)
+			clinic.emit(Event{Kind: FellAsleep, Actor: me})
+			clinic.sleep(ctx, record, clinic.rooms)
+			continue
+		}
+
+		clinic.emit(Event{Kind: PatientFound, Actor: me, Patient: patient.Patient, Priority: priority})
+		clinic.treat(ctx, patient, record)
+	}
+}
+
+/**
+ * The dentist sleeps until a patient wakes them up to be treated, or the
+ * clinic closes. A sleeping dentist listens to the waiting room as well as
+ * to dent (wait, or the priority rooms when given), so a patient sitting
+ * down right after the dentist found the room empty still wakes them up,
+ * rather than waiting on a sleeping dentist until the next patient rings.
+ * A booking dentist (see booking) also wakes up for the next slot.
+ */
+func (clinic *practice) sleep(ctx context.Context, record *DentistReport, rooms *waitingRooms) {
+	me := Actor{Role: DentistRole, ID: record.ID}
+	asleep := clinic.clock.Now()
+	wake := func(patient *Session) {
+		slept := clinic.clock.Now().Sub(asleep)
+		record.Asleep += slept
+		record.WakeUps++
+		clinic.emit(Event{Kind: WokeUp, Actor: me, Patient: patient.Patient, Duration: slept})
+	}
+
+	var timer Timer
+	if clinic.booking() {
+		timer = clinic.clock.NewTimer(0)
+		timer.Stop()
+		defer timer.Stop()
+	}
+
+	for {
+		wait, changed, slot := clinic.wait, (<-chan struct{})(nil), (<-chan time.Time)(nil)
+		if rooms != nil {
+			// Watched before looking, so a patient sitting down in between still wakes us up
+			wait, changed = nil, rooms.watch()
+			if patient, priority, found := rooms.next(clinic.clock.Now()); found {
+				wake(patient)
+				// Only the dentist of part 2 tells which level they found patients at
+				if clinic.config.Scenario == PriorityScenario {
+					clinic.emit(Event{Kind: PatientFound, Actor: me, Patient: patient.Patient, Priority: priority})
+				}
+				clinic.treat(ctx, patient, record)
+				return
+			}
+		}
+		if timer != nil {
+			changed = clinic.rooms.watch()
+			if patient, found := clinic.appointment(me); found {
+				wake(patient)
+				clinic.treat(ctx, patient, record)
+				return
+			}
+			if next, pending := clinic.rooms.nextSlot(); pending {
+				timer.Reset(next.Sub(clinic.clock.Now()))
+				slot = timer.C()
+			}
+		}
+
+		select {
+		case newlyArrivedPatient := <-clinic.dent:
+			wake(newlyArrivedPatient)
+			clinic.treat(ctx, newlyArrivedPatient, record)
+			return
+		case waitingPatient := <-wait:
+			wake(waitingPatient)
+			clinic.treat(ctx, waitingPatient, record)
+			return
+		case <-changed:
+		case <-slot:
+		case <-ctx.Done():
+			record.Asleep += clinic.clock.Now().Sub(asleep)
+			return
+		}
+	}
+}
+
+/**
+ * Emulates a treatment operation activity, walking the patient through
+ * every step of the session's protocol. The work itself comes once the
+ * patient is prepared for it (started, numbed, X-rayed...).
+ */
+func (clinic *practice) treat(ctx context.Context, patient *Session, record *DentistReport) {
+	me := Actor{Role: DentistRole, ID: record.ID}
+	began := clinic.clock.Now()
+	done := func() {
+		busy := clinic.clock.Now().Sub(began)
+		record.Busy += busy
+		clinic.emit(Event{Kind: TreatmentDone, Actor: me, Patient: patient.Patient, Priority: patient.Priority, Duration: busy})
+	}
+	started, worked := false, false
+	for _, step := range patient.Protocol() {
+		if !worked && !step.preparation() {
+			// Emulate dentist treatment activity
+			dentistTreatmentActivity(clinic.clock, patient)
+			worked = true
+		}
+
+		// Once the patient is in the chair the treatment is seen through,
+		// but a closing clinic sends the patient home before it starts.
+		if err := patient.Perform(ctx, step); err != nil {
+			var outOfSync *ProtocolError
+			if errors.As(err, &outOfSync) {
+				clinic.emit(Event{Kind: ProtocolFailed, Actor: me, Patient: patient.Patient, Step: outOfSync.Expected, Err: err.Error()})
+			}
+			patient.Reject()
+			// A patient sent home before the treatment started kept nobody busy
+			if started {
+				done()
+			}
+			return
+		}
+		started = true
+		clinic.emit(Event{Kind: StepPerformed, Actor: me, Patient: patient.Patient, Priority: patient.Priority, Step: step})
+	}
+
+	done()
+	record.Treated++
+}
+
+/**
+ * The dentistTreatmentActivity is a time-consuming action (i.e. pausing
+ * the current goroutine for as long as the patient's treatment takes.)
+ */
+func dentistTreatmentActivity(clock Clock, patient *Session) {
+	clock.Sleep(patient.Duration)
+}
@@ -0,0 +1,147 @@
+package clinic
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTreat(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// Plays the patient's side of the session, once the dentist is on it
+		patient  func(session *Session)
+		rejected bool
+		closed   bool
+		// What the dentist tells of the treatment
+		events  []EventKind
+		busy    time.Duration
+		treated int
+	}{
+		{
+			name: "treated",
+			patient: func(session *Session) {
+				for {
+					if _, err := session.Await(context.Background()); err != nil {
+						return
+					}
+				}
+			},
+			events:  []EventKind{StepPerformed, StepPerformed, StepPerformed, TreatmentDone},
+			busy:    2 * time.Second,
+			treated: 1,
+		},
+		{
+			name:     "rejected before the start",
+			patient:  func(session *Session) {},
+			rejected: true,
+		},
+		{
+			name:    "sent home as the clinic closes",
+			patient: func(session *Session) {},
+			closed:  true,
+		},
+		{
+			name: "leaving in the middle of the treatment",
+			patient: func(session *Session) {
+				session.Await(context.Background())
+				session.Reject()
+			},
+			events: []EventKind{StepPerformed, TreatmentDone},
+			busy:   2 * time.Second,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var events []EventKind
+			config := Config{Scenario: DentistScenario}
+			policy, _ := config.policy()
+			clinic := &practice{
+				config: config,
+				clock:  NewVirtualClock(time.Unix(0, 0)),
+				sink:   SinkFunc(func(event Event) { events = append(events, event.Kind) }),
+				levels: config.levels(),
+				rooms:  newWaitingRooms([]Level{{Name: "wait", Capacity: 1}}, policy, rand.New(rand.NewSource(1))),
+			}
+
+			ctx, closeClinic := context.WithCancel(context.Background())
+			defer closeClinic()
+			if test.closed {
+				closeClinic()
+			}
+
+			session := NewSession(1, StandardProtocol)
+			session.Duration = 2 * time.Second
+			defer session.Reject()
+			if test.rejected {
+				session.Reject()
+			}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				test.patient(session)
+			}()
+
+			record := DentistReport{ID: 1}
+			clinic.treat(ctx, session, &record)
+			session.Reject()
+			<-done
+
+			if !slices.Equal(events, test.events) {
+				t.Errorf("dentist told %v, want %v", events, test.events)
+			}
+			if record.Busy != test.busy || record.Treated != test.treated {
+				t.Errorf("dentist was busy %v and treated %d, want %v and %d", record.Busy, record.Treated, test.busy, test.treated)
+			}
+		})
+	}
+}
+
+/**
+ * One dentist, patients walking in at 2s, 3s and 10s for a 2s treatment
+ * each: asleep until 2s, busy until 6s with the patient who came in at 3s
+ * waiting their turn, asleep again until 10s and busy until 12s.
+ */
+func TestDentistReportOverARun(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		t.Run(scenario.String(), func(t *testing.T) {
+			config := Config{
+				Scenario:    scenario,
+				Assistants:  1,
+				WaitSize:    3,
+				HWaitSize:   3,
+				LWaitSize:   3,
+				AgingLimit:  time.Minute,
+				LowPatients: 3,
+				Arrivals:    Replay{2 * time.Second, 3 * time.Second, 10 * time.Second},
+				Durations:   Durations{Default: Fixed(2 * time.Second)},
+				Clock:       NewVirtualClock(time.Unix(0, 0)),
+				Seed:        1,
+				Sink:        Sinks{},
+			}
+			report, err := Run(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Elapsed != 12*time.Second {
+				t.Errorf("open for %v, want 12s", report.Elapsed)
+			}
+			want := []DentistReport{{ID: 1, Treated: 3, WakeUps: 2, Busy: 6 * time.Second, Asleep: 6 * time.Second}}
+			if !slices.Equal(report.Dentists, want) {
+				t.Fatalf("dentists = %+v, want %+v", report.Dentists, want)
+			}
+			dentist := report.Dentists[0]
+			if idle := dentist.Idle(report.Elapsed); idle != 6*time.Second {
+				t.Errorf("idle for %v, want 6s", idle)
+			}
+			if utilisation := dentist.Utilisation(report.Elapsed); utilisation != 50 {
+				t.Errorf("utilisation = %g%%, want 50%%", utilisation)
+			}
+			if throughput := dentist.Throughput(report.Elapsed); throughput != 15 {
+				t.Errorf("throughput = %g a minute, want 15", throughput)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package clinic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/** treatment durations **********************************************/
+
+/**
+ * A duration model draws how long a single treatment takes.
+ */
+type DurationModel interface {
+	Duration(random *rand.Rand) time.Duration
+}
+
+/**
+ * Treatments last anywhere between Min and Max, all equally likely.
+ */
+type Uniform struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (model Uniform) Duration(random *rand.Rand) time.Duration {
+	if model.Max <= model.Min {
+		return model.Min
+	}
+	return model.Min + time.Duration(random.Int63n(int64(model.Max-model.Min)+1))
+}
+
+/**
+ * Treatments are memoryless and last Mean on average.
+ */
+type Exponential struct {
+	Mean time.Duration
+}
+
+func (model Exponential) Duration(random *rand.Rand) time.Duration {
+	return positive(random.ExpFloat64() * float64(model.Mean))
+}
+
+/**
+ * Treatments last Mean give or take StdDev, never less than zero.
+ */
+type Normal struct {
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+func (model Normal) Duration(random *rand.Rand) time.Duration {
+	return positive(float64(model.Mean) + random.NormFloat64()*float64(model.StdDev))
+}
+
+/**
+ * Treatments usually last around Median, with a long tail of slow ones
+ * that grows with Sigma (the standard deviation of the log duration).
+ */
+type LogNormal struct {
+	Median time.Duration
+	Sigma  float64
+}
+
+func (model LogNormal) Duration(random *rand.Rand) time.Duration {
+	return positive(float64(model.Median) * math.Exp(model.Sigma*random.NormFloat64()))
+}
+
+/**
+ * Treatments always last the same.
+ */
+type Fixed time.Duration
+
+func (model Fixed) Duration(*rand.Rand) time.Duration {
+	return time.Duration(model)
+}
+
+func positive(duration float64) time.Duration {
+	if duration < 0 {
+		return 0
+	}
+	return time.Duration(duration)
+}
+
+/** treatment types **************************************************/
+
+/**
+ * The kind of treatment a patient comes in for.
+ */
+type Treatment string
+
+/**
+ * The kinds of treatment the clinic knows of. Patients come in for a
+ * Checkup unless told otherwise.
+ */
+const (
+	Checkup    Treatment = "checkup"
+	Cleaning   Treatment = "cleaning"
+	Filling    Treatment = "filling"
+	Extraction Treatment = "extraction"
+	RootCanal  Treatment = "root-canal"
+)
+
+/**
+ * A treatment type tells how long a kind of treatment usually takes, and
+ * the steps preparing the patient before the work itself, on top of the
+ * run's protocol.
+ */
+type TreatmentType struct {
+	Duration    DurationModel
+	Preparation []TreatmentStep
+}
+
+/**
+ * The kinds of treatment the clinic knows of, on the same toy scale as the
+ * check-up's 1 to 3 seconds (the range every treatment used to take).
+ */
+var TreatmentTypes = map[Treatment]TreatmentType{
+	Checkup:    {Duration: Uniform{Min: time.Second, Max: 3 * time.Second}},
+	Cleaning:   {Duration: Uniform{Min: 2 * time.Second, Max: 4 * time.Second}},
+	Filling:    {Duration: LogNormal{Median: 4 * time.Second, Sigma: 0.3}, Preparation: []TreatmentStep{Anaesthetic}},
+	Extraction: {Duration: LogNormal{Median: 5 * time.Second, Sigma: 0.5}, Preparation: []TreatmentStep{XRay, Anaesthetic}},
+	RootCanal:  {Duration: Normal{Mean: 10 * time.Second, StdDev: 2 * time.Second}, Preparation: []TreatmentStep{XRay, Anaesthetic}},
+}
+
+/**
+ * The steps a treatment goes through: its own protocol if the run has one
+ * for it, or else the run's protocol along with the treatment type's
+ * preparation.
+ */
+func (config Config) protocol(treatment Treatment) Protocol {
+	if protocol, known := config.Protocols[treatment]; known {
+		return protocol
+	}
+	return config.Protocol.With(TreatmentTypes[treatment].Preparation...)
+}
+
+/**
+ * Durations tells how long each kind of treatment takes. Treatments
+ * without a model of their own in ByTreatment take the Default one, or
+ * else the model of their TreatmentTypes when Default is left empty.
+ */
+type Durations struct {
+	Default     DurationModel
+	ByTreatment map[Treatment]DurationModel
+}
+
+/**
+ * The durations of a run left empty: every treatment takes the model of
+ * its TreatmentTypes
+ */
+var DefaultDurations = Durations{}
+
+/**
+ * The duration model of a treatment, a check-up's for a treatment of no
+ * known type
+ */
+func (durations Durations) For(treatment Treatment) DurationModel {
+	if model, known := durations.ByTreatment[treatment]; known {
+		return model
+	}
+	if durations.Default != nil {
+		return durations.Default
+	}
+	if model := TreatmentTypes[treatment].Duration; model != nil {
+		return model
+	}
+	return TreatmentTypes[Checkup].Duration
+}
+
+/**
+ * A treatment mix weighs how often patients come in for each treatment,
+ * e.g. {Checkup: 3, "filling": 1} sends one patient in four for a filling.
+ */
+type TreatmentMix map[Treatment]float64
+
+/**
+ * Draws the treatment of the next patient from the mix. Treatments are
+ * walked in name order so the same random numbers give the same patients.
+ */
+func (mix TreatmentMix) Draw(random *rand.Rand) Treatment {
+	treatments := make([]Treatment, 0, len(mix))
+	total := 0.0
+	for treatment, weight := range mix {
+		if weight > 0 {
+			treatments = append(treatments, treatment)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return Checkup
+	}
+	sort.Slice(treatments, func(i, j int) bool { return treatments[i] < treatments[j] })
+
+	draw := random.Float64() * total
+	for _, treatment := range treatments {
+		if draw -= mix[treatment]; draw < 0 {
+			return treatment
+		}
+	}
+	return treatments[len(treatments)-1]
+}
+
+/** parsing **********************************************************/
+
+/**
+ * Parses the name of one of the TreatmentTypes, e.g. "root-canal".
+ */
+func ParseTreatment(name string) (Treatment, error) {
+	treatment := Treatment(name)
+	if _, known := TreatmentTypes[treatment]; !known {
+		return "", fmt.Errorf("clinic: unknown treatment %q", name)
+	}
+	return treatment, nil
+}
+
+/**
+ * Parses a treatment mix as treatment:weight pairs, e.g.
+ * "checkup:3,filling:1" sends one patient in four for a filling.
+ */
+func ParseTreatmentMix(spec string) (TreatmentMix, error) {
+	mix := TreatmentMix{}
+	for _, pair := range strings.Split(spec, ",") {
+		treatment, weight, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || treatment == "" {
+			return nil, fmt.Errorf("clinic: treatment mix %q is not treatment:weight,...", spec)
+		}
+		known, err := ParseTreatment(treatment)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			return nil, fmt.Errorf("clinic: treatment mix %q %w", spec, err)
+		}
+		mix[known] = parsed
+	}
+	return mix, nil
+}
+
+func (mix TreatmentMix) String() string {
+	treatments := make([]Treatment, 0, len(mix))
+	for treatment := range mix {
+		treatments = append(treatments, treatment)
+	}
+	sort.Slice(treatments, func(i, j int) bool { return treatments[i] < treatments[j] })
+
+	pairs := make([]string, len(treatments))
+	for i, treatment := range treatments {
+		pairs[i] = fmt.Sprintf("%s:%g", treatment, mix[treatment])
+	}
+	return strings.Join(pairs, ",")
+}
+
+/**
+ * Parses a duration model from its name and parameters, as in:
+ *   • "uniform:1s,3s" for Uniform{Min: 1s, Max: 3s}
+ *   • "exponential:2s" for Exponential{Mean: 2s}
+ *   • "normal:2s,500ms" for Normal{Mean: 2s, StdDev: 500ms}
+ *   • "lognormal:2s,0.5" for LogNormal{Median: 2s, Sigma: 0.5}
+ *   • "fixed:2s" for Fixed(2s)
+ */
+func ParseDurationModel(spec string) (DurationModel, error) {
+	name, parameters, _ := strings.Cut(spec, ":")
+	arguments := strings.Split(parameters, ",")
+
+	var err error
+	arity := func(count int) bool {
+		if len(arguments) != count {
+			err = fmt.Errorf("takes %d parameters", count)
+		}
+		return err == nil
+	}
+	duration := func(i int) (parsed time.Duration) {
+		if err == nil {
+			parsed, err = time.ParseDuration(strings.TrimSpace(arguments[i]))
+		}
+		return parsed
+	}
+	number := func(i int) (parsed float64) {
+		if err == nil {
+			parsed, err = strconv.ParseFloat(strings.TrimSpace(arguments[i]), 64)
+		}
+		return parsed
+	}
+
+	var model DurationModel
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "uniform":
+		if arity(2) {
+			model = Uniform{Min: duration(0), Max: duration(1)}
+		}
+	case "exponential", "exp":
+		if arity(1) {
+			model = Exponential{Mean: duration(0)}
+		}
+	case "normal":
+		if arity(2) {
+			model = Normal{Mean: duration(0), StdDev: duration(1)}
+		}
+	case "lognormal":
+		if arity(2) {
+			model = LogNormal{Median: duration(0), Sigma: number(1)}
+		}
+	case "fixed":
+		if arity(1) {
+			model = Fixed(duration(0))
+		}
+	default:
+		err = errors.New("is unknown")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("clinic: duration model %q %w", spec, err)
+	}
+	return model, nil
+}
+
+func (model Uniform) String() string {
+	return fmt.Sprintf("uniform:%s,%s", model.Min, model.Max)
+}
+
+func (model Exponential) String() string {
+	return fmt.Sprintf("exponential:%s", model.Mean)
+}
+
+func (model Normal) String() string {
+	return fmt.Sprintf("normal:%s,%s", model.Mean, model.StdDev)
+}
+
+func (model LogNormal) String() string {
+	return fmt.Sprintf("lognormal:%s,%g", model.Median, model.Sigma)
+}
+
+func (model Fixed) String() string {
+	return fmt.Sprintf("fixed:%s", time.Duration(model))
+}
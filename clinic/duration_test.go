@@ -0,0 +1,120 @@
+package clinic
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseDurationModel(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		model DurationModel
+		fails bool
+	}{
+		{spec: "uniform:1s,3s", model: Uniform{Min: time.Second, Max: 3 * time.Second}},
+		{spec: "Uniform: 1s, 3s", model: Uniform{Min: time.Second, Max: 3 * time.Second}},
+		{spec: "exponential:2s", model: Exponential{Mean: 2 * time.Second}},
+		{spec: "exp:2s", model: Exponential{Mean: 2 * time.Second}},
+		{spec: "normal:2s,500ms", model: Normal{Mean: 2 * time.Second, StdDev: 500 * time.Millisecond}},
+		{spec: "lognormal:2s,0.5", model: LogNormal{Median: 2 * time.Second, Sigma: 0.5}},
+		{spec: "fixed:2s", model: Fixed(2 * time.Second)},
+		{spec: "uniform:1s", fails: true},
+		{spec: "exponential:2s,1s", fails: true},
+		{spec: "normal:2s,wide", fails: true},
+		{spec: "lognormal:2s,s", fails: true},
+		{spec: "fixed", fails: true},
+		{spec: "gamma:2s,1", fails: true},
+	} {
+		model, err := ParseDurationModel(test.spec)
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("ParseDurationModel(%q) = %v, want an error", test.spec, model)
+		case !test.fails && err != nil:
+			t.Errorf("ParseDurationModel(%q) failed: %v", test.spec, err)
+		case !test.fails && model != test.model:
+			t.Errorf("ParseDurationModel(%q) = %#v, want %#v", test.spec, model, test.model)
+		}
+	}
+}
+
+func TestDurationModelStrings(t *testing.T) {
+	for _, spec := range []string{"uniform:1s,3s", "exponential:2s", "normal:2s,500ms", "lognormal:2s,0.5", "fixed:2s"} {
+		model, err := ParseDurationModel(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if printed := model.(interface{ String() string }).String(); printed != spec {
+			t.Errorf("%#v prints as %q, want %q", model, printed, spec)
+		}
+	}
+}
+
+func TestDurationModelsStayInRange(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		model DurationModel
+		min   time.Duration
+		max   time.Duration
+	}{
+		{Uniform{Min: time.Second, Max: 3 * time.Second}, time.Second, 3 * time.Second},
+		{Uniform{Min: 2 * time.Second, Max: time.Second}, 2 * time.Second, 2 * time.Second},
+		{Normal{Mean: time.Second, StdDev: 2 * time.Second}, 0, time.Hour},
+		{Exponential{Mean: time.Second}, 0, time.Hour},
+		{Fixed(time.Second), time.Second, time.Second},
+	} {
+		for range 1000 {
+			if duration := test.model.Duration(random); duration < test.min || duration > test.max {
+				t.Fatalf("%v drew %v, want between %v and %v", test.model, duration, test.min, test.max)
+			}
+		}
+	}
+}
+
+func TestDurationsFor(t *testing.T) {
+	filling := LogNormal{Median: 20 * time.Minute, Sigma: 0.4}
+	for _, test := range []struct {
+		durations Durations
+		treatment Treatment
+		model     DurationModel
+	}{
+		{Durations{}, Checkup, Uniform{Min: time.Second, Max: 3 * time.Second}},
+		{DefaultDurations, Filling, TreatmentTypes[Filling].Duration},
+		{Durations{}, "whitening", TreatmentTypes[Checkup].Duration},
+		{Durations{}, Filling, TreatmentTypes[Filling].Duration},
+		{Durations{Default: Fixed(20 * time.Millisecond)}, Checkup, Fixed(20 * time.Millisecond)},
+		{Durations{Default: Fixed(20 * time.Millisecond)}, Filling, Fixed(20 * time.Millisecond)},
+		{Durations{ByTreatment: map[Treatment]DurationModel{Filling: filling}}, Filling, filling},
+		{Durations{Default: Fixed(0), ByTreatment: map[Treatment]DurationModel{Filling: filling}}, Filling, filling},
+		{Durations{Default: Fixed(0), ByTreatment: map[Treatment]DurationModel{Filling: filling}}, RootCanal, Fixed(0)},
+	} {
+		if model := test.durations.For(test.treatment); model != test.model {
+			t.Errorf("%+v.For(%s) = %v, want %v", test.durations, test.treatment, model, test.model)
+		}
+	}
+}
+
+func TestParseTreatmentMix(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		mix   TreatmentMix
+		fails bool
+	}{
+		{spec: "checkup:3,filling:1", mix: TreatmentMix{Checkup: 3, Filling: 1}},
+		{spec: "root-canal:0.5, extraction:0.5", mix: TreatmentMix{RootCanal: 0.5, Extraction: 0.5}},
+		{spec: "checkup", fails: true},
+		{spec: "checkup:often", fails: true},
+		{spec: "whitening:1", fails: true},
+		{spec: "Filling:1", fails: true},
+	} {
+		mix, err := ParseTreatmentMix(test.spec)
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("ParseTreatmentMix(%q) = %v, want an error", test.spec, mix)
+		case !test.fails && err != nil:
+			t.Errorf("ParseTreatmentMix(%q) failed: %v", test.spec, err)
+		case !test.fails && mix.String() != test.mix.String():
+			t.Errorf("ParseTreatmentMix(%q) = %v, want %v", test.spec, mix, test.mix)
+		}
+	}
+}
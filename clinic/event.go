@@ -0,0 +1,201 @@
+package clinic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** events **********************************************************/
+
+/**
+ * What happened in the clinic.
+ */
+type EventKind string
+
+const (
+	// The clinic opens (with the run's seed) and closes
+	ClinicOpened EventKind = "clinic-opened"
+	ClinicClosed EventKind = "clinic-closed"
+
+	// A member of staff falls asleep, wakes up, or leaves as the clinic closes
+	FellAsleep EventKind = "fell-asleep"
+	WokeUp     EventKind = "woke-up"
+	Closing    EventKind = "closing"
+
+	// A patient arrives, wakes a dentist up, or queues in a waiting room, and
+	// leaves treated, sent home, turned away by a full room (balked) or
+	// tired of waiting (reneged)
+	PatientArrived  EventKind = "patient-arrived"
+	PatientWokeUp   EventKind = "patient-woke-dentist"
+	PatientQueued   EventKind = "patient-queued"
+	PatientLeft     EventKind = "patient-left"
+	PatientSentHome EventKind = "patient-sent-home"
+	PatientBalked   EventKind = "patient-balked"
+	PatientReneged  EventKind = "patient-reneged"
+
+	// A booked patient walks in too late to be seen at their slot, or never
+	// shows up for their appointment
+	PatientLate       EventKind = "patient-late"
+	AppointmentMissed EventKind = "appointment-missed"
+
+	// A priority patient is found by the dentist, placed in the dentist's
+	// waiting room by the assistant, or aged one level up (e.g. lwait to hwait)
+	PatientFound  EventKind = "patient-found"
+	PatientPlaced EventKind = "patient-placed"
+	PatientAged   EventKind = "patient-aged"
+
+	// A treatment step is performed by the dentist (once the patient took it),
+	// or received by the patient
+	StepPerformed EventKind = "step-performed"
+	StepReceived  EventKind = "step-received"
+
+	// A dentist is done with a treatment that started (after Duration in the chair),
+	// or done for the day once the clinic closes (with a summary of their work)
+	TreatmentDone   EventKind = "treatment-done"
+	DentistFinished EventKind = "dentist-finished"
+
+	// A treatment session fell out of sync
+	ProtocolFailed EventKind = "protocol-failed"
+)
+
+/**
+ * Who is acting in the clinic.
+ */
+type Role string
+
+const (
+	ClinicRole    Role = "clinic"
+	DentistRole   Role = "dentist"
+	PatientRole   Role = "patient"
+	AssistantRole Role = "assistant"
+)
+
+/**
+ * An actor is a member of staff or a patient. Dentists and patients are
+ * numbered from 1, an id of 0 stands for all of them (or the only one).
+ */
+type Actor struct {
+	Role Role `json:"role"`
+	ID   int  `json:"id,omitempty"`
+}
+
+func (actor Actor) String() string {
+	name := strings.ToUpper(string(actor.Role[:1])) + string(actor.Role[1:])
+	if actor.ID == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%d)", name, actor.ID)
+}
+
+/**
+ * How urgent a patient is, from 0 (the least urgent) up to the run's top
+ * level. With the two levels of parts 2 and 3, high priority patients
+ * queue in hwait and low priority ones in lwait.
+ */
+type Priority int
+
+const (
+	LowPriority Priority = iota
+	HighPriority
+)
+
+func (priority Priority) String() string {
+	switch priority {
+	case LowPriority:
+		return "low"
+	case HighPriority:
+		return "high"
+	default:
+		return fmt.Sprintf("level %d", int(priority))
+	}
+}
+
+/**
+ * How many patients are queuing in each waiting room. HWait and LWait are
+ * the rooms of the most and least urgent levels, Levels has every level's
+ * room, the least urgent first.
+ */
+type QueueDepths struct {
+	Wait   int   `json:"wait"`
+	HWait  int   `json:"hwait"`
+	LWait  int   `json:"lwait"`
+	Levels []int `json:"levels,omitempty"`
+}
+
+/**
+ * An event is one thing happening in the clinic, at a given time. Fields
+ * that do not apply to an event's kind are left empty.
+ */
+type Event struct {
+	Kind     EventKind
+	Time     time.Time
+	Actor    Actor
+	Patient  int
+	Priority Priority
+	// The name of the priority level, for events about a patient
+	Level  string
+	Step   TreatmentStep
+	Queues QueueDepths
+	Seed   int64
+	Err    string
+
+	// How long something took: asleep for WokeUp, in the chair for
+	// TreatmentDone, and the whole run for DentistFinished
+	Duration time.Duration
+	// The dentist's work over the whole run, for DentistFinished
+	Dentist *DentistReport
+}
+
+/** sinks **********************************************************/
+
+/**
+ * A sink receives every event of a run, from many goroutines at once.
+ */
+type Sink interface {
+	Publish(event Event)
+}
+
+/**
+ * Turns a function into a sink.
+ */
+type SinkFunc func(event Event)
+
+func (publish SinkFunc) Publish(event Event) {
+	publish(event)
+}
+
+/**
+ * Publishes every event to all of its sinks, in order.
+ */
+type Sinks []Sink
+
+func (sinks Sinks) Publish(event Event) {
+	for _, sink := range sinks {
+		sink.Publish(event)
+	}
+}
+
+/**
+ * Keeps every event it receives, mostly useful to inspect a run afterwards.
+ */
+type Recorder struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (recorder *Recorder) Publish(event Event) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.events = append(recorder.events, event)
+}
+
+/**
+ * The events received so far, in the order they were published
+ */
+func (recorder *Recorder) Events() []Event {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]Event(nil), recorder.events...)
+}
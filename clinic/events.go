@@ -0,0 +1,67 @@
+package clinic
+
+/** events **********************************************************/
+
+// Dentist log events
+var WentToSleep = yellow + "%s is sleeping. (no patients)" + clear
+var WakesUp = yellow + "%s woke up." + clear
+var DentistNotBusy = green + "%s will be treated right away. (Dentist is not busy)" + clear
+var StartTreatingPatient = green + "%s is treating the patient." + clear
+var ChecksPatientTeeth = purple + "%s finished the surgery! Dentist checks patient teeth <=" + clear
+var GivesAnaesthetic = blue + "%s numbs the patient's mouth." + clear
+var TakesXRay = blue + "%s takes an X-ray of the patient's teeth." + clear
+var ClosingTheClinic = red + "%s is closing the clinic. (Sending waiting patients home)" + clear
+
+// Priority log events
+var FoundAPriorityPatient = cyan + "%s found a %s priority patient." + clear
+var FoundALessUrgentPatient = cyan + "%s found a %s priority patient while no more urgent patients were available." + clear
+var MovingAPatientUp = cyan + "%s is moving one patient up to %s priority." + clear
+
+// Patient log events
+var RequestTreatment = blue + "%s requested a treatment." + clear
+var WaitingForTreatment = red + "%s have to wait for treatment. (Dentist is not ready yet)" + clear
+var IsGettingTreated = yellow + "%s is getting treated. (They have been put to sleep until surgery is complete)" + clear
+var ShineTeeth = purple + "=> %s has shiny teeth!" + clear
+var FeelsNumb = blue + "%s can no longer feel their teeth." + clear
+var HoldsStill = blue + "%s holds still for the X-ray." + clear
+var LeaveClinic = gray + "%s is leaving the clinic." + clear
+var SentHome = gray + "%s is leaving the clinic untreated. (The clinic is closed)" + clear
+var Balked = gray + "%s is leaving the clinic untreated. (The waiting room is full)" + clear
+var Reneged = gray + "%s is leaving the clinic untreated. (Tired of waiting)" + clear
+var LateForAppointment = red + "%s is late for their appointment. (Waiting along with walk-ins)" + clear
+var MissedAppointment = gray + "%s did not show up for their appointment." + clear
+
+// Panic log events
+var DentistIsNotReady = red + "Sorry, I am not ready yet..." + clear
+var TreatmentMustBeInSync = red + "Wait! Are you sure you're a dentist???" + clear
+var TreatmentIsComplete = red + "Aren't we finished? Can I leave please?" + clear
+var GetOffTheChair = red + "We're done here, can you get off the chair please?" + clear
+
+// Assistant log events
+var PlacingAPriorityPatient = cyan + "%s placed a %s priority patient in the waiting area" + clear
+
+// Clinic log events
+var ClinicIsOpen = gray + "%s is open." + clear
+var ClinicIsClosed = gray + "%s is closed. (%d patients treated in %v)" + clear
+var PatientsNotTreated = red + "%s could not treat patients %v." + clear
+var PatientsWalkedOut = red + "%s saw %d patients leave at the sight of a full waiting room and %d tired of waiting." + clear
+var AppointmentsKept = gray + "%s saw %d booked patients, %d of them late, and %d did not show up." + clear
+var DentistThroughput = gray + "%s treated %d patients. (%.2f patients per minute)" + clear
+var DentistUtilisation = gray + "%s was busy %v, idle %v (asleep %v) and woke up %d times. (%.1f%% utilisation)" + clear
+var PatientsWaited = gray + "%s waited min %v, mean %v, p50 %v, p95 %v, p99 %v, max %v. (%d patients)" + clear
+var PatientsStayed = gray + "%s stayed min %v, mean %v, p50 %v, p95 %v, p99 %v, max %v. (%d patients)" + clear
+var PatientsInChair = gray + "%s were in the chair min %v, mean %v, p50 %v, p95 %v, p99 %v, max %v. (%d patients treated)" + clear
+
+// Treatment step log events, as seen by the dentist and by the patient
+var dentistSteps = map[TreatmentStep]string{
+	Start:       StartTreatingPatient,
+	QA:          ChecksPatientTeeth,
+	Anaesthetic: GivesAnaesthetic,
+	XRay:        TakesXRay,
+}
+var patientSteps = map[TreatmentStep]string{
+	Start:       IsGettingTreated,
+	QA:          ShineTeeth,
+	Anaesthetic: FeelsNumb,
+	XRay:        HoldsStill,
+}
@@ -0,0 +1,327 @@
+package clinic
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/** scenario files **********************************************************/
+
+/**
+ * A scenario file describes a whole run, so experiment setups can be shared
+ * as files rather than hand-edited mains. Files are YAML, or JSON when
+ * named *.json, e.g.
+ *
+ *   scenario: assistant
+ *   seed: 42
+ *   dentists: 2
+ *   assistants: 1
+ *   rooms: {wait: 15, hwait: 20, lwait: 10}
+ *   balking: true
+ *   patience: 45s
+ *   policy: aging
+ *   aging: 500ms
+ *   patients: {high: 20, low: 10}
+ *   opening: 2s
+ *   arrivals: poisson:0.5
+ *   treatments: {checkup: 3, filling: 1, root-canal: 0.5}
+ *   durations:
+ *     default: uniform:1s,3s
+ *     filling: lognormal:20m,0.4
+ *   protocol: [start, qa, finish]
+ *   protocols:
+ *     extraction: [start, x-ray, anaesthetic, qa, x-ray, finish]
+ *   timeout: 2m
+ *
+ * Triage categories can replace hwait/lwait and the high/low patients:
+ *
+ *   levels:
+ *     - {name: check-up, capacity: 10, patients: 12}
+ *     - {name: routine, capacity: 10, patients: 8}
+ *     - {name: urgent, capacity: 5, patients: 4}
+ *     - {name: emergency, capacity: 2, patients: 1}
+ *
+ * Patients can be booked ahead, every interval (count,interval[,first] as
+ * the -appointments flag) or at given slots, at a level (the least urgent
+ * when not set):
+ *
+ *   appointments:
+ *     slots: 6,10s,5s
+ *     level: routine
+ *     booked:
+ *       - {slot: 12s, level: urgent, treatment: filling}
+ *     tolerance: 2s
+ *     early: 1s
+ *     lateness: exponential:2s
+ *     no-shows: 0.1
+ *
+ * Durations are keyed by treatment, "default" standing for every other one
+ * (their treatment types' durations being the default when it is missing).
+ * Duration models, arrival processes and scheduling policies are written
+ * as their flags are.
+ */
+type ScenarioFile struct {
+	Scenario   string `yaml:"scenario" json:"scenario"`
+	Seed       int64  `yaml:"seed" json:"seed"`
+	Dentists   int    `yaml:"dentists" json:"dentists"`
+	Assistants int    `yaml:"assistants" json:"assistants"`
+
+	Rooms struct {
+		Wait  int `yaml:"wait" json:"wait"`
+		HWait int `yaml:"hwait" json:"hwait"`
+		LWait int `yaml:"lwait" json:"lwait"`
+	} `yaml:"rooms" json:"rooms"`
+	Balking  bool   `yaml:"balking" json:"balking"`
+	Patience string `yaml:"patience" json:"patience"`
+	Policy   string `yaml:"policy" json:"policy"`
+	Aging    string `yaml:"aging" json:"aging"`
+
+	Patients struct {
+		High int `yaml:"high" json:"high"`
+		Low  int `yaml:"low" json:"low"`
+	} `yaml:"patients" json:"patients"`
+	// Priority levels replacing hwait/lwait and the high/low patients, least urgent first
+	Levels []struct {
+		Name     string `yaml:"name" json:"name"`
+		Capacity int    `yaml:"capacity" json:"capacity"`
+		Patients int    `yaml:"patients" json:"patients"`
+	} `yaml:"levels" json:"levels"`
+	Opening  string `yaml:"opening" json:"opening"`
+	Arrivals string `yaml:"arrivals" json:"arrivals"`
+
+	Appointments struct {
+		Slots  string `yaml:"slots" json:"slots"`
+		Level  string `yaml:"level" json:"level"`
+		Booked []struct {
+			Slot      string `yaml:"slot" json:"slot"`
+			Level     string `yaml:"level" json:"level"`
+			Treatment string `yaml:"treatment" json:"treatment"`
+		} `yaml:"booked" json:"booked"`
+		Tolerance string  `yaml:"tolerance" json:"tolerance"`
+		Early     string  `yaml:"early" json:"early"`
+		Lateness  string  `yaml:"lateness" json:"lateness"`
+		NoShows   float64 `yaml:"no-shows" json:"no-shows"`
+	} `yaml:"appointments" json:"appointments"`
+
+	Treatments map[string]float64 `yaml:"treatments" json:"treatments"`
+	Durations  map[string]string  `yaml:"durations" json:"durations"`
+	Protocol   []string           `yaml:"protocol" json:"protocol"`
+	// Steps of the treatments with a protocol of their own
+	Protocols map[string][]string `yaml:"protocols" json:"protocols"`
+
+	// How long the clinic stays open at most, not part of the Config
+	Timeout string `yaml:"timeout" json:"timeout"`
+}
+
+/**
+ * Reads a scenario file, rejecting any setting it does not know.
+ */
+func LoadScenarioFile(path string) (ScenarioFile, error) {
+	var file ScenarioFile
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+	}
+	if err != nil {
+		return file, fmt.Errorf("clinic: %s: %w", path, err)
+	}
+	return file, nil
+}
+
+/**
+ * The run the file describes, along with its timeout (0 when not set).
+ */
+func (file ScenarioFile) Config() (Config, time.Duration, error) {
+	config := Config{
+		Seed:         file.Seed,
+		Dentists:     file.Dentists,
+		Assistants:   file.Assistants,
+		WaitSize:     file.Rooms.Wait,
+		HWaitSize:    file.Rooms.HWait,
+		LWaitSize:    file.Rooms.LWait,
+		Balking:      file.Balking,
+		HighPatients: file.Patients.High,
+		LowPatients:  file.Patients.Low,
+	}
+
+	for _, level := range file.Levels {
+		config.Levels = append(config.Levels, Level{Name: level.Name, Capacity: level.Capacity, Patients: level.Patients})
+	}
+
+	var err error
+	if config.Scenario, err = ParseScenario(file.Scenario); err != nil {
+		return config, 0, err
+	}
+
+	var timeout time.Duration
+	for _, setting := range []struct {
+		name string
+		text string
+		into *time.Duration
+	}{
+		{"aging", file.Aging, &config.AgingLimit},
+		{"patience", file.Patience, &config.Patience},
+		{"opening", file.Opening, &config.Opening},
+		{"tolerance", file.Appointments.Tolerance, &config.Appointments.Tolerance},
+		{"early", file.Appointments.Early, &config.Appointments.Early},
+		{"timeout", file.Timeout, &timeout},
+	} {
+		if setting.text == "" {
+			continue
+		}
+		if *setting.into, err = time.ParseDuration(setting.text); err != nil {
+			return config, 0, fmt.Errorf("clinic: scenario %s %w", setting.name, err)
+		}
+	}
+
+	if file.Arrivals != "" {
+		if config.Arrivals, err = ParseArrivalProcess(file.Arrivals); err != nil {
+			return config, 0, err
+		}
+	}
+
+	if err = file.appointments(&config); err != nil {
+		return config, 0, err
+	}
+
+	if file.Policy != "" {
+		if config.Policy, err = ParseSchedulingPolicy(file.Policy); err != nil {
+			return config, 0, err
+		}
+	}
+
+	if len(file.Treatments) > 0 {
+		config.Treatments = TreatmentMix{}
+		for name, weight := range file.Treatments {
+			treatment, err := ParseTreatment(name)
+			if err != nil {
+				return config, 0, err
+			}
+			config.Treatments[treatment] = weight
+		}
+	}
+
+	for name, spec := range file.Durations {
+		model, err := ParseDurationModel(spec)
+		if err != nil {
+			return config, 0, err
+		}
+		if name == "default" {
+			config.Durations.Default = model
+			continue
+		}
+		treatment, err := ParseTreatment(name)
+		if err != nil {
+			return config, 0, err
+		}
+		if config.Durations.ByTreatment == nil {
+			config.Durations.ByTreatment = map[Treatment]DurationModel{}
+		}
+		config.Durations.ByTreatment[treatment] = model
+	}
+
+	if config.Protocol, err = parseProtocol(file.Protocol); err != nil {
+		return config, 0, err
+	}
+	for name, names := range file.Protocols {
+		treatment, err := ParseTreatment(name)
+		if err != nil {
+			return config, 0, err
+		}
+		protocol, err := parseProtocol(names)
+		if err != nil {
+			return config, 0, err
+		}
+		if config.Protocols == nil {
+			config.Protocols = map[Treatment]Protocol{}
+		}
+		config.Protocols[Treatment(treatment)] = protocol
+	}
+
+	return config, timeout, nil
+}
+
+/**
+ * A protocol from the names of its steps, nil when there are none
+ */
+func parseProtocol(names []string) (Protocol, error) {
+	var protocol Protocol
+	for _, name := range names {
+		step, err := ParseTreatmentStep(name)
+		if err != nil {
+			return nil, err
+		}
+		protocol = append(protocol, step)
+	}
+	return protocol, nil
+}
+
+/**
+ * Books the file's appointments into the run, once its levels are known
+ */
+func (file ScenarioFile) appointments(config *Config) error {
+	book := file.Appointments
+	config.Appointments.NoShows = book.NoShows
+
+	var err error
+	if book.Lateness != "" {
+		if config.Appointments.Lateness, err = ParseDurationModel(book.Lateness); err != nil {
+			return err
+		}
+	}
+
+	level := func(name string) (Priority, error) {
+		if name == "" {
+			return 0, nil
+		}
+		return config.priority(name)
+	}
+
+	if book.Slots != "" {
+		priority, err := level(book.Level)
+		if err != nil {
+			return err
+		}
+		slots, err := ParseSlots(book.Slots, priority)
+		if err != nil {
+			return err
+		}
+		config.Appointments.Booked = append(config.Appointments.Booked, slots...)
+	}
+
+	for _, booked := range book.Booked {
+		appointment := Appointment{}
+		if booked.Treatment != "" {
+			if appointment.Treatment, err = ParseTreatment(booked.Treatment); err != nil {
+				return err
+			}
+		}
+		if appointment.Slot, err = time.ParseDuration(booked.Slot); err != nil {
+			return fmt.Errorf("clinic: scenario appointment %w", err)
+		}
+		if appointment.Priority, err = level(cmp.Or(booked.Level, book.Level)); err != nil {
+			return err
+		}
+		config.Appointments.Booked = append(config.Appointments.Booked, appointment)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package clinic
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadTheScenarios(t *testing.T) {
+	for _, test := range []struct {
+		path    string
+		config  Config
+		timeout time.Duration
+	}{
+		{
+			path: "part1.yaml",
+			config: Config{Scenario: DentistScenario, Dentists: 1, WaitSize: 5, LowPatients: 10,
+				Opening: 2 * time.Second, Arrivals: FixedInterval(time.Second)},
+			timeout: 42 * time.Second,
+		},
+		{
+			path: "part2.yaml",
+			config: Config{Scenario: PriorityScenario, Dentists: 1, HWaitSize: 50, LWaitSize: 5,
+				AgingLimit: 3 * time.Second, HighPatients: 20, LowPatients: 10},
+			timeout: 150 * time.Second,
+		},
+		{
+			path: "part3.json",
+			config: Config{Scenario: AssistantScenario, Dentists: 1, Assistants: 1, WaitSize: 15, HWaitSize: 20, LWaitSize: 10,
+				AgingLimit: 500 * time.Millisecond, HighPatients: 20, LowPatients: 10},
+			timeout: 150 * time.Second,
+		},
+	} {
+		file, err := LoadScenarioFile(filepath.Join("..", "scenarios", test.path))
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+			continue
+		}
+		config, timeout, err := file.Config()
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(config, test.config) || timeout != test.timeout {
+			t.Errorf("%s describes %+v (timeout %v), want %+v (timeout %v)", test.path, config, timeout, test.config, test.timeout)
+		}
+	}
+}
+
+func write(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScenarioFileSettings(t *testing.T) {
+	path := write(t, "every.yaml", `
+scenario: priority
+seed: 7
+dentists: 2
+levels:
+  - {name: routine, capacity: 10, patients: 8}
+  - {name: urgent, capacity: 5, patients: 4}
+balking: true
+patience: 45s
+policy: fair:1,3
+aging: 1s
+opening: 2s
+arrivals: poisson:0.5
+treatments: {checkup: 3, filling: 1}
+durations:
+  default: fixed:2s
+  filling: lognormal:20m,0.4
+protocol: [start, qa, finish]
+protocols:
+  filling: [start, anaesthetic, qa, finish]
+appointments:
+  slots: 2,10s
+  level: urgent
+  booked:
+    - {slot: 1m, treatment: filling}
+  tolerance: 2s
+  early: 1s
+  lateness: exponential:2s
+  no-shows: 0.1
+timeout: 2m
+`)
+	file, err := LoadScenarioFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, timeout, err := file.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		Scenario: PriorityScenario,
+		Seed:     7,
+		Dentists: 2,
+		Levels: []Level{
+			{Name: "routine", Capacity: 10, Patients: 8},
+			{Name: "urgent", Capacity: 5, Patients: 4},
+		},
+		Balking:    true,
+		Patience:   45 * time.Second,
+		Policy:     WeightedFair{Weights: []float64{1, 3}},
+		AgingLimit: time.Second,
+		Opening:    2 * time.Second,
+		Arrivals:   Poisson{Rate: 0.5},
+		Treatments: TreatmentMix{Checkup: 3, Filling: 1},
+		Durations: Durations{
+			Default:     Fixed(2 * time.Second),
+			ByTreatment: map[Treatment]DurationModel{Filling: LogNormal{Median: 20 * time.Minute, Sigma: 0.4}},
+		},
+		Protocol:  StandardProtocol,
+		Protocols: map[Treatment]Protocol{Filling: {Start, Anaesthetic, QA, Finish}},
+		Appointments: AppointmentBook{
+			Booked: []Appointment{
+				{Slot: 10 * time.Second, Priority: 1},
+				{Slot: 20 * time.Second, Priority: 1},
+				{Slot: time.Minute, Priority: 1, Treatment: Filling},
+			},
+			Tolerance: 2 * time.Second,
+			Early:     time.Second,
+			Lateness:  Exponential{Mean: 2 * time.Second},
+			NoShows:   0.1,
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("file describes\n%+v\nwant\n%+v", config, want)
+	}
+	if timeout != 2*time.Minute {
+		t.Errorf("file times out after %v, want 2m", timeout)
+	}
+}
+
+func TestScenarioFileMistakes(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		// Whether the mistake is caught reading the file, or making a config of it
+		loading bool
+	}{
+		{"unknown.yaml", "scenario: dentist\nwaiting: 5\n", true},
+		{"unknown.json", `{"scenario": "dentist", "waiting": 5}`, true},
+		{"malformed.yaml", "scenario: [dentist\n", true},
+		{"scenario.yaml", "scenario: surgery\n", false},
+		{"duration.yaml", "scenario: dentist\naging: soon\n", false},
+		{"arrivals.yaml", "scenario: dentist\narrivals: random\n", false},
+		{"policy.yaml", "scenario: priority\npolicy: random\n", false},
+		{"model.yaml", "scenario: dentist\ndurations: {default: forever}\n", false},
+		{"step.yaml", "scenario: dentist\nprotocol: [start, polish, finish]\n", false},
+		{"level.yaml", "scenario: priority\nappointments: {slots: '2,10s', level: vip}\n", false},
+		{"slots.yaml", "scenario: priority\nappointments: {slots: '-2,10s'}\n", false},
+		{"treatments.yaml", "scenario: dentist\ntreatments: {whitening: 1}\n", false},
+		{"durations.yaml", "scenario: dentist\ndurations: {whitening: fixed:1s}\n", false},
+		{"protocols.yaml", "scenario: dentist\nprotocols: {whitening: [start, finish]}\n", false},
+		{"booked.yaml", "scenario: priority\nappointments: {booked: [{slot: 1m, treatment: whitening}]}\n", false},
+	} {
+		file, err := LoadScenarioFile(write(t, test.name, test.content))
+		if test.loading {
+			if err == nil {
+				t.Errorf("%s: loaded, want an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if _, _, err := file.Config(); err == nil {
+			t.Errorf("%s: made a config, want an error", test.name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package clinic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/u-ways/go-channels/internal/cpu"
+)
+
+/**
+ * An open clinic of the scenario nobody walks in for the idle time
+ */
+func idleClinic(scenario Scenario, idle time.Duration) Config {
+	return Config{
+		Scenario:    scenario,
+		Dentists:    2,
+		Assistants:  2,
+		WaitSize:    15,
+		HWaitSize:   20,
+		LWaitSize:   10,
+		AgingLimit:  500 * time.Millisecond,
+		LowPatients: 1,
+		Opening:     idle,
+		Durations:   Durations{Default: Fixed(0)},
+		Seed:        1,
+		Sink:        Sinks{},
+	}
+}
+
+/**
+ * Runs the clinic, telling how long it took and how much CPU it used
+ */
+func idleRun(config Config) (elapsed, used time.Duration, err error) {
+	before, _ := cpu.Time()
+	began := time.Now()
+	_, err = Run(context.Background(), config)
+	elapsed = time.Since(began)
+	after, _ := cpu.Time()
+	return elapsed, after - before, err
+}
+
+/**
+ * The staff of an open clinic nobody walked in yet should all be asleep,
+ * not polling their rooms: the run may burn a sliver of one core at most.
+ */
+func TestIdleStaffBurnNoCPU(t *testing.T) {
+	if _, measured := cpu.Time(); !measured {
+		t.Skip("CPU time cannot be measured on this platform")
+	}
+
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		t.Run(scenario.String(), func(t *testing.T) {
+			elapsed, used, err := idleRun(idleClinic(scenario, 300*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if used > elapsed/20 {
+				t.Errorf("idle for %v, the staff used %v of CPU, want under 5%% of one core", elapsed.Round(time.Millisecond), used)
+			}
+		})
+	}
+}
+
+/**
+ * How much CPU an idle clinic burns per second nobody walks in, e.g.
+ *   go test ./clinic -run - -bench IdleClinic
+ */
+func BenchmarkIdleClinic(b *testing.B) {
+	if _, measured := cpu.Time(); !measured {
+		b.Skip("CPU time cannot be measured on this platform")
+	}
+
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		b.Run(scenario.String(), func(b *testing.B) {
+			var elapsed, used time.Duration
+			for range b.N {
+				ran, burnt, err := idleRun(idleClinic(scenario, 100*time.Millisecond))
+				if err != nil {
+					b.Fatal(err)
+				}
+				elapsed, used = elapsed+ran, used+burnt
+			}
+			b.ReportMetric(float64(used)/elapsed.Seconds(), "cpu-ns/idle-s")
+		})
+	}
+}
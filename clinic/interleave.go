@@ -0,0 +1,249 @@
+package clinic
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+/** wake-up checkpoints **********************************************/
+
+/**
+ * A checkpoint is a point of the wake-up protocol between two actors'
+ * moves, where another actor could slip in: the windows a lost wake-up
+ * would fall through.
+ */
+type Checkpoint string
+
+const (
+	// A dentist (or an assistant) found every waiting room empty, and is about to sleep
+	FoundNoPatient Checkpoint = "found no patient waiting"
+	// A patient found no dentist asleep on dent, and is about to queue
+	FoundDentistBusy Checkpoint = "found the dentist busy"
+	// An assistant took a patient out of the rooms, and is about to place them in wait
+	TookPatient Checkpoint = "took a patient to place"
+)
+
+/**
+ * Pauses an actor at a checkpoint, when the run is being interleaved
+ */
+func (clinic *practice) checkpoint(actor Actor, point Checkpoint) {
+	if clinic.pause != nil {
+		clinic.pause(actor, point)
+	}
+}
+
+/** interleaving harness *********************************************/
+
+/**
+ * A pause of an actor at a checkpoint, for as many ticks of the virtual
+ * clock. Actors pausing for fewer ticks move on first.
+ */
+type Pause struct {
+	Actor Actor
+	Point Checkpoint
+	Ticks int
+}
+
+func (pause Pause) String() string {
+	return fmt.Sprintf("%s %s (+%d)", pause.Actor, pause.Point, pause.Ticks)
+}
+
+/**
+ * An interleaving is every pause of a run, in the order actors reached
+ * their checkpoint.
+ */
+type Interleaving []Pause
+
+/**
+ * A workload of the harness: how many ticks apart patients walk in, and
+ * how many ticks their treatments take.
+ */
+type Workload struct {
+	Gap       int
+	Treatment int
+}
+
+func (workload Workload) String() string {
+	return fmt.Sprintf("patients %d ticks apart, treated in %d ticks", workload.Gap, workload.Treatment)
+}
+
+/**
+ * The workloads every scenario is interleaved under. Patients walking in
+ * together or apart, and treatments over before or after the other actors'
+ * pauses, open different windows for a wake-up to be lost in.
+ */
+var Workloads = []Workload{
+	{Gap: 0, Treatment: 10},
+	{Gap: 0, Treatment: 1},
+	{Gap: 0, Treatment: 2},
+	{Gap: 1, Treatment: 1},
+	{Gap: 1, Treatment: 3},
+	{Gap: 2, Treatment: 1},
+	{Gap: 3, Treatment: 2},
+}
+
+/**
+ * The outcome of running a scenario under every interleaving of its checkpoints
+ */
+type WakeUpCheck struct {
+	// Number of interleavings run
+	Explored int
+	// Whether every interleaving was run, rather than stopping at the limit
+	Exhaustive bool
+	// The interleavings in which a patient was never treated, along with
+	// who and under which workload
+	Stranded  []Interleaving
+	Patients  [][]int
+	Workloads []Workload
+}
+
+/**
+ * How long a tick of the harness lasts on the virtual clock, and how long
+ * a stranded patient is given before the clinic closes on them
+ */
+const (
+	tick        = time.Millisecond
+	closingTime = time.Hour
+)
+
+/**
+ * Runs a scenario under every interleaving of its wake-up checkpoints, for
+ * each of the Workloads, at most limit runs in all, and reports the ones
+ * stranding a patient: leaving them in a waiting room, untreated, while a
+ * dentist sleeps.
+ *
+ * Every actor reaching a checkpoint pauses for one or two ticks of a
+ * virtual clock, which only moves on once every goroutine is blocked, and
+ * then releases one actor at a time. Which actor moves on first at each
+ * checkpoint is then down to the ticks, and every combination of ticks
+ * (keyed by the actor and how many checkpoints they passed) is run.
+ *
+ * The config's clock, sink, arrivals, durations and seed are replaced.
+ */
+func CheckWakeUps(ctx context.Context, config Config, limit int) (WakeUpCheck, error) {
+	config.Opening = 0
+	config.ArrivalInterval = 0
+	config.Sink = Sinks{}
+	config.Seed = 1
+	if config.Policy == nil {
+		// Aging would add timers of its own to the run
+		config.Policy = StrictPriority{}
+	}
+
+	check := WakeUpCheck{Exhaustive: true}
+	for _, workload := range Workloads {
+		config.Arrivals = FixedInterval(time.Duration(workload.Gap) * tick)
+		config.Durations = Durations{Default: Fixed(time.Duration(workload.Treatment) * tick)}
+		config.Treatments = nil
+		if err := explore(ctx, config, workload, limit, &check); err != nil || !check.Exhaustive {
+			return check, err
+		}
+	}
+	return check, nil
+}
+
+/**
+ * Runs a scenario under every interleaving of its wake-up checkpoints for
+ * a workload, adding to the check, until it explored limit runs in all.
+ */
+func explore(ctx context.Context, config Config, workload Workload, limit int, check *WakeUpCheck) error {
+	// Ticks chosen for the checkpoints of the runs still to explore
+	pending := []map[string]int{{}}
+	for len(pending) > 0 {
+		if check.Explored >= limit {
+			check.Exhaustive = false
+			return nil
+		}
+		chosen := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		report, interleaving, reached, err := interleave(ctx, config, chosen)
+		if err != nil {
+			return err
+		}
+		check.Explored++
+		if len(report.Untreated) > 0 {
+			check.Stranded = append(check.Stranded, interleaving)
+			check.Patients = append(check.Patients, report.Untreated)
+			check.Workloads = append(check.Workloads, workload)
+		}
+
+		// Branches on every checkpoint reached but not chosen yet,
+		// which paused for one tick in this run
+		var open []string
+		for _, key := range reached {
+			if _, known := chosen[key]; !known {
+				open = append(open, key)
+			}
+		}
+		for i, key := range open {
+			branch := make(map[string]int, len(chosen)+i+1)
+			for known, ticks := range chosen {
+				branch[known] = ticks
+			}
+			for _, before := range open[:i] {
+				branch[before] = 1
+			}
+			branch[key] = 2
+			pending = append(pending, branch)
+		}
+	}
+	return nil
+}
+
+/**
+ * Runs a scenario once, pausing actors at their checkpoints for the chosen
+ * ticks (one tick unless chosen). Returns the run's report, its pauses and
+ * every checkpoint reached, keyed as the chosen ticks are.
+ */
+func interleave(ctx context.Context, config Config, chosen map[string]int) (Report, Interleaving, []string, error) {
+	clock := NewVirtualClock(time.Time{})
+	config.Clock = clock
+
+	var mutex sync.Mutex
+	var interleaving Interleaving
+	var reached []string
+	passed := map[Actor]int{}
+
+	pause := func(actor Actor, point Checkpoint) {
+		mutex.Lock()
+		key := fmt.Sprintf("%s#%d", actor, passed[actor])
+		passed[actor]++
+		ticks, known := chosen[key]
+		if !known {
+			ticks = 1
+		}
+		reached = append(reached, key)
+		interleaving = append(interleaving, Pause{Actor: actor, Point: point, Ticks: ticks})
+		mutex.Unlock()
+
+		clock.Sleep(time.Duration(ticks) * tick)
+	}
+
+	// Closes the clinic on stranded patients, on the virtual clock
+	ctx, closeClinic := context.WithCancel(ctx)
+	defer closeClinic()
+	closing := clock.NewTimer(closingTime)
+	defer closing.Stop()
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-closing.C():
+			closeClinic()
+		case <-closed:
+		}
+	}()
+
+	report, err := run(ctx, config, pause)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	// Checkpoints are branched on in a set order, whichever order they were reached in
+	sort.Strings(reached)
+	return report, slices.Clone(interleaving), reached, err
+}
@@ -0,0 +1,32 @@
+package clinic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNoInterleavingStrandsAPatient(t *testing.T) {
+	for _, config := range []Config{
+		{Scenario: DentistScenario, WaitSize: 3, LowPatients: 3},
+		{Scenario: PriorityScenario, HWaitSize: 3, LWaitSize: 3, LowPatients: 2, HighPatients: 1},
+		{Scenario: AssistantScenario, WaitSize: 3, HWaitSize: 3, LWaitSize: 3, LowPatients: 2, HighPatients: 1},
+	} {
+		t.Run(config.Scenario.String(), func(t *testing.T) {
+			limit := 100000
+			if testing.Short() {
+				limit = 200
+			}
+
+			check, err := CheckWakeUps(context.Background(), config, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !check.Exhaustive && !testing.Short() {
+				t.Errorf("stopped after %d interleavings, before running every one of them", check.Explored)
+			}
+			for i, interleaving := range check.Stranded {
+				t.Errorf("patients %v stranded (%s) after %v", check.Patients[i], check.Workloads[i], interleaving)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package clinic
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+/** json lines **********************************************************/
+
+/**
+ * A sink writing every event as one JSON object per line, e.g.
+ *
+ *   {"time":"…","action":"patient-queued","actor":{"role":"patient","id":3},
+ *    "patient":3,"priority":"low","queues":{"wait":0,"hwait":2,"lwait":1,"levels":[1,2]}}
+ *
+ * Writing stops at the first error, which Err reports.
+ */
+type JSONLSink struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+/**
+ * Creates a JSON lines sink writing to out.
+ */
+func NewJSONLSink(out io.Writer) *JSONLSink {
+	return &JSONLSink{encoder: json.NewEncoder(out)}
+}
+
+/**
+ * The line written for an event. Priorities only apply to events about a
+ * patient, and steps to events about a treatment step.
+ */
+type jsonlRecord struct {
+	Time     time.Time   `json:"time"`
+	Action   EventKind   `json:"action"`
+	Actor    Actor       `json:"actor"`
+	Patient  int         `json:"patient,omitempty"`
+	Priority string      `json:"priority,omitempty"`
+	Step     string      `json:"step,omitempty"`
+	Queues   QueueDepths `json:"queues"`
+	Seed     int64       `json:"seed,omitempty"`
+	Err      string      `json:"error,omitempty"`
+	// Durations are in seconds
+	Duration float64       `json:"duration,omitempty"`
+	Dentist  *jsonlDentist `json:"dentist,omitempty"`
+}
+
+type jsonlDentist struct {
+	Treated     int     `json:"treated"`
+	WakeUps     int     `json:"wake_ups"`
+	Busy        float64 `json:"busy"`
+	Asleep      float64 `json:"asleep"`
+	Idle        float64 `json:"idle"`
+	Utilisation float64 `json:"utilisation"`
+}
+
+func (sink *JSONLSink) Publish(event Event) {
+	record := jsonlRecord{
+		Time:     event.Time,
+		Action:   event.Kind,
+		Actor:    event.Actor,
+		Patient:  event.Patient,
+		Queues:   event.Queues,
+		Seed:     event.Seed,
+		Err:      event.Err,
+		Duration: event.Duration.Seconds(),
+	}
+	if event.Patient != 0 {
+		record.Priority = event.Level
+	}
+	switch event.Kind {
+	case StepPerformed, StepReceived, ProtocolFailed:
+		record.Step = event.Step.String()
+	}
+	if dentist := event.Dentist; dentist != nil {
+		record.Dentist = &jsonlDentist{
+			Treated:     dentist.Treated,
+			WakeUps:     dentist.WakeUps,
+			Busy:        dentist.Busy.Seconds(),
+			Asleep:      dentist.Asleep.Seconds(),
+			Idle:        dentist.Idle(event.Duration).Seconds(),
+			Utilisation: dentist.Utilisation(event.Duration),
+		}
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if sink.err == nil {
+		sink.err = sink.encoder.Encode(record)
+	}
+}
+
+/**
+ * The first error met writing events, if any
+ */
+func (sink *JSONLSink) Err() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.err
+}
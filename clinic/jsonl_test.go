@@ -0,0 +1,133 @@
+package clinic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+/**
+ * A line of the JSON lines sink, read back the way a consumer of the
+ * file would
+ */
+type jsonlLine struct {
+	Time     time.Time `json:"time"`
+	Action   EventKind `json:"action"`
+	Actor    Actor     `json:"actor"`
+	Patient  int       `json:"patient"`
+	Priority string    `json:"priority"`
+	Queues   struct {
+		Wait  int `json:"wait"`
+		HWait int `json:"hwait"`
+		LWait int `json:"lwait"`
+	} `json:"queues"`
+}
+
+func TestJSONLSinkWritesEveryEventAsALine(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		t.Run(scenario.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			sink := NewJSONLSink(&out)
+			recorder := &Recorder{}
+			config := Config{
+				Scenario:        scenario,
+				Assistants:      1,
+				WaitSize:        3,
+				HWaitSize:       2,
+				LWaitSize:       2,
+				AgingLimit:      time.Minute,
+				HighPatients:    2,
+				LowPatients:     2,
+				ArrivalInterval: 100 * time.Millisecond,
+				Durations:       Durations{Default: Fixed(time.Second)},
+				Clock:           NewVirtualClock(time.Unix(0, 0)),
+				Seed:            1,
+				Sink:            Sinks{recorder, sink},
+			}
+			if _, err := Run(context.Background(), config); err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			events := recorder.Events()
+			lines := bufio.NewScanner(&out)
+			queued, count := false, 0
+			for i := 0; lines.Scan(); i++ {
+				count++
+				var line jsonlLine
+				if err := json.Unmarshal(lines.Bytes(), &line); err != nil {
+					t.Fatalf("line %d %q: %v", i+1, lines.Text(), err)
+				}
+				if i >= len(events) {
+					t.Fatalf("line %d %q has no event", i+1, lines.Text())
+				}
+				event := events[i]
+
+				priority := ""
+				if event.Patient != 0 {
+					priority = event.Level
+				}
+				if !line.Time.Equal(event.Time) || line.Action != event.Kind || line.Actor != event.Actor ||
+					line.Patient != event.Patient || line.Priority != priority ||
+					line.Queues.Wait != event.Queues.Wait || line.Queues.HWait != event.Queues.HWait || line.Queues.LWait != event.Queues.LWait {
+					t.Errorf("line %d = %+v, want the event %+v", i+1, line, event)
+				}
+				queued = queued || line.Queues.Wait+line.Queues.HWait+line.Queues.LWait > 0
+			}
+			if err := lines.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if count != len(events) {
+				t.Errorf("%d lines for %d events", count, len(events))
+			}
+			if !queued {
+				t.Error("no line saw a patient waiting, want the waiting rooms filling up")
+			}
+		})
+	}
+}
+
+/**
+ * Writes the first few bytes, then fails
+ */
+type failingWriter struct {
+	left int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (writer *failingWriter) Write(data []byte) (int, error) {
+	if len(data) > writer.left {
+		return 0, errDiskFull
+	}
+	writer.left -= len(data)
+	return len(data), nil
+}
+
+func TestJSONLSinkReportsTheFirstWriteError(t *testing.T) {
+	writer := &failingWriter{left: 200}
+	sink := NewJSONLSink(writer)
+
+	sink.Publish(Event{Kind: ClinicOpened, Actor: Actor{Role: ClinicRole}})
+	if err := sink.Err(); err != nil {
+		t.Fatalf("Err() = %v after a line that fit, want nil", err)
+	}
+	sink.Publish(Event{Kind: ClinicOpened, Actor: Actor{Role: ClinicRole}})
+	sink.Publish(Event{Kind: ClinicOpened, Actor: Actor{Role: ClinicRole}})
+	if err := sink.Err(); !errors.Is(err, errDiskFull) {
+		t.Errorf("Err() = %v, want %v", err, errDiskFull)
+	}
+
+	// Nothing is written past the first error
+	writer.left = 1 << 20
+	sink.Publish(Event{Kind: ClinicOpened, Actor: Actor{Role: ClinicRole}})
+	if writer.left != 1<<20 {
+		t.Error("the sink kept writing after an error")
+	}
+}
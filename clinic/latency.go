@@ -0,0 +1,204 @@
+package clinic
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+/** patient latencies **********************************************/
+
+/**
+ * PatientReport describes the visit of a single patient: when they asked
+ * for a treatment, when the treatment started and when they left.
+ */
+type PatientReport struct {
+	ID       int
+	Priority Priority
+	// The name of the priority level the patient came in at
+	Level     string
+	Treatment Treatment
+	// Whether the patient left treated, rather than sent home, and if
+	// not whether they left on finding the waiting room full (balked)
+	// or ran out of patience (reneged)
+	Treated bool
+	Balked  bool
+	Reneged bool
+	// When the patient was booked for (zero for walk-ins), and whether they
+	// walked in too late to be seen at their slot
+	Slot time.Time
+	Late bool
+
+	Arrived time.Time
+	// When the treatment started, zero for patients never treated
+	Started time.Time
+	Left    time.Time
+}
+
+/**
+ * How long the patient waited for the treatment to start,
+ * or to be sent home if it never did.
+ */
+func (patient PatientReport) Waiting() time.Duration {
+	if patient.Started.IsZero() {
+		return patient.Left.Sub(patient.Arrived)
+	}
+	return patient.Started.Sub(patient.Arrived)
+}
+
+/**
+ * How long the patient spent in the chair, zero if never treated.
+ */
+func (patient PatientReport) InTreatment() time.Duration {
+	if patient.Started.IsZero() {
+		return 0
+	}
+	return patient.Left.Sub(patient.Started)
+}
+
+/**
+ * How long the patient spent in the clinic (the sojourn time).
+ */
+func (patient PatientReport) InSystem() time.Duration {
+	return patient.Left.Sub(patient.Arrived)
+}
+
+/**
+ * Distribution describes a set of durations.
+ */
+type Distribution struct {
+	Count int
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+/**
+ * Describes a set of durations, using nearest-rank percentiles.
+ */
+func Distribute(durations []time.Duration) Distribution {
+	if len(durations) == 0 {
+		return Distribution{}
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, duration := range sorted {
+		total += duration
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return sorted[max(rank, 1)-1]
+	}
+
+	return Distribution{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(50),
+		P95:   percentile(95),
+		P99:   percentile(99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+/**
+ * LatencyReport describes the latencies of a priority level. Waiting and
+ * sojourn times cover every patient, so starved patients sent home count
+ * with the time they spent waiting. Treatment times cover treated ones.
+ */
+type LatencyReport struct {
+	Priority    Priority
+	Level       string
+	Waiting     Distribution
+	InTreatment Distribution
+	InSystem    Distribution
+}
+
+/**
+ * The latencies of each priority level patients came in at,
+ * the most urgent first.
+ */
+func (report Report) Latencies() []LatencyReport {
+	levels := map[Priority]string{}
+	for _, patient := range report.Patients {
+		levels[patient.Priority] = patient.Level
+	}
+	priorities := make([]Priority, 0, len(levels))
+	for priority := range levels {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] > priorities[j] })
+
+	var latencies []LatencyReport
+	for _, priority := range priorities {
+		var waiting, inTreatment, inSystem []time.Duration
+		for _, patient := range report.Patients {
+			if patient.Priority != priority {
+				continue
+			}
+			waiting = append(waiting, patient.Waiting())
+			inSystem = append(inSystem, patient.InSystem())
+			if patient.Treated {
+				inTreatment = append(inTreatment, patient.InTreatment())
+			}
+		}
+
+		latencies = append(latencies, LatencyReport{
+			Priority:    priority,
+			Level:       levels[priority],
+			Waiting:     Distribute(waiting),
+			InTreatment: Distribute(inTreatment),
+			InSystem:    Distribute(inSystem),
+		})
+	}
+	return latencies
+}
+
+/**
+ * TreatmentReport describes the patients who came in for a kind of
+ * treatment: how many of them there were, and how long the ones treated
+ * were in the chair.
+ */
+type TreatmentReport struct {
+	Treatment   Treatment
+	Patients    int
+	InTreatment Distribution
+}
+
+/**
+ * What each kind of treatment patients came in for took, in name order
+ */
+func (report Report) Treatments() []TreatmentReport {
+	byTreatment := map[Treatment][]PatientReport{}
+	for _, patient := range report.Patients {
+		byTreatment[patient.Treatment] = append(byTreatment[patient.Treatment], patient)
+	}
+	treatments := make([]Treatment, 0, len(byTreatment))
+	for treatment := range byTreatment {
+		treatments = append(treatments, treatment)
+	}
+	sort.Slice(treatments, func(i, j int) bool { return treatments[i] < treatments[j] })
+
+	reports := make([]TreatmentReport, 0, len(treatments))
+	for _, treatment := range treatments {
+		var inTreatment []time.Duration
+		for _, patient := range byTreatment[treatment] {
+			if patient.Treated {
+				inTreatment = append(inTreatment, patient.InTreatment())
+			}
+		}
+		reports = append(reports, TreatmentReport{
+			Treatment:   treatment,
+			Patients:    len(byTreatment[treatment]),
+			InTreatment: Distribute(inTreatment),
+		})
+	}
+	return reports
+}
@@ -0,0 +1,86 @@
+package clinic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDistribute(t *testing.T) {
+	seconds := func(counts ...int) []time.Duration {
+		durations := make([]time.Duration, len(counts))
+		for i, count := range counts {
+			durations[i] = time.Duration(count) * time.Second
+		}
+		return durations
+	}
+	hundred := make([]int, 100)
+	for i := range hundred {
+		// 100 down to 1, so the durations have to be sorted
+		hundred[i] = 100 - i
+	}
+
+	for _, test := range []struct {
+		name         string
+		durations    []time.Duration
+		distribution Distribution
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:      "one",
+			durations: seconds(7),
+			distribution: Distribution{Count: 1, Min: 7 * time.Second, Mean: 7 * time.Second,
+				P50: 7 * time.Second, P95: 7 * time.Second, P99: 7 * time.Second, Max: 7 * time.Second},
+		},
+		{
+			name:      "four",
+			durations: seconds(4, 1, 3, 2),
+			distribution: Distribution{Count: 4, Min: time.Second, Mean: 2500 * time.Millisecond,
+				P50: 2 * time.Second, P95: 4 * time.Second, P99: 4 * time.Second, Max: 4 * time.Second},
+		},
+		{
+			name:      "a hundred",
+			durations: seconds(hundred...),
+			distribution: Distribution{Count: 100, Min: time.Second, Mean: 50500 * time.Millisecond,
+				P50: 50 * time.Second, P95: 95 * time.Second, P99: 99 * time.Second, Max: 100 * time.Second},
+		},
+	} {
+		if distribution := Distribute(test.durations); distribution != test.distribution {
+			t.Errorf("%s: Distribute(%v) = %+v, want %+v", test.name, test.durations, distribution, test.distribution)
+		}
+	}
+}
+
+func TestDistributeLeavesItsInputAlone(t *testing.T) {
+	durations := []time.Duration{3, 1, 2}
+	Distribute(durations)
+	if durations[0] != 3 || durations[1] != 1 || durations[2] != 2 {
+		t.Errorf("Distribute sorted its input, %v", durations)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	opened := time.Unix(0, 0)
+	at := func(seconds int) time.Time {
+		return opened.Add(time.Duration(seconds) * time.Second)
+	}
+	report := Report{Patients: []PatientReport{
+		{ID: 1, Priority: LowPriority, Level: "low", Treated: true, Arrived: at(0), Started: at(4), Left: at(6)},
+		{ID: 2, Priority: HighPriority, Level: "high", Treated: true, Arrived: at(1), Started: at(2), Left: at(4)},
+		{ID: 3, Priority: LowPriority, Level: "low", Arrived: at(2), Left: at(10)},
+	}}
+
+	latencies := report.Latencies()
+	if len(latencies) != 2 || latencies[0].Level != "high" || latencies[1].Level != "low" {
+		t.Fatalf("got latencies %+v, want high then low", latencies)
+	}
+	low := latencies[1]
+	// The patient sent home counts with the time they waited
+	if low.Waiting.Count != 2 || low.Waiting.Max != 8*time.Second || low.Waiting.Min != 4*time.Second {
+		t.Errorf("low priority waited %+v, want 4s and 8s", low.Waiting)
+	}
+	if low.InTreatment.Count != 1 || low.InTreatment.Max != 2*time.Second {
+		t.Errorf("low priority were in treatment %+v, want the one treated patient's 2s", low.InTreatment)
+	}
+}
@@ -0,0 +1,66 @@
+package clinic
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+/** loggers **********************************************************/
+
+/**
+ * A log function summarising a clinic run
+ */
+func ReportLog(report Report) {
+	log.SetFlags(log.Ltime)
+	var clinic = fmt.Sprintf("%s (seed %d)", "Clinic", report.Seed)
+	log.Printf(ClinicIsClosed, clinic, len(report.Treated), report.Elapsed.Round(time.Millisecond))
+	if len(report.Untreated) > 0 {
+		log.Printf(PatientsNotTreated, clinic, report.Untreated)
+	}
+	if len(report.Balked) > 0 || len(report.Reneged) > 0 {
+		log.Printf(PatientsWalkedOut, clinic, len(report.Balked), len(report.Reneged))
+	}
+	if booked := report.booked(); booked > 0 || len(report.NoShows) > 0 {
+		log.Printf(AppointmentsKept, clinic, booked, len(report.Late), len(report.NoShows))
+	}
+	for _, dentist := range report.Dentists {
+		var name = fmt.Sprintf("%s (%d)", "Dentist", dentist.ID)
+		log.Printf(DentistThroughput, name, dentist.Treated, dentist.Throughput(report.Elapsed))
+		log.Printf(DentistUtilisation, name,
+			dentist.Busy.Round(time.Millisecond), dentist.Idle(report.Elapsed).Round(time.Millisecond),
+			dentist.Asleep.Round(time.Millisecond), dentist.WakeUps, dentist.Utilisation(report.Elapsed))
+	}
+
+	latencies := report.Latencies()
+	for _, latency := range latencies {
+		var patients = fmt.Sprintf("%s priority patients", strings.ToUpper(latency.Level))
+		if len(latencies) == 1 {
+			patients = "Patients"
+		}
+		distributionLog(PatientsWaited, patients, latency.Waiting)
+		distributionLog(PatientsStayed, patients, latency.InSystem)
+	}
+
+	// Each kind of treatment is only told apart when patients came in for several
+	if treatments := report.Treatments(); len(treatments) > 1 {
+		for _, treatment := range treatments {
+			var patients = fmt.Sprintf("%s patients", strings.ToUpper(string(treatment.Treatment)))
+			distributionLog(PatientsInChair, patients, treatment.InTreatment)
+		}
+	}
+}
+
+/**
+ * A log function summarising a distribution of durations
+ */
+func distributionLog(action string, name string, distribution Distribution) {
+	round := func(duration time.Duration) time.Duration {
+		return duration.Round(time.Millisecond)
+	}
+	log.Printf(action, name,
+		round(distribution.Min), round(distribution.Mean), round(distribution.P50),
+		round(distribution.P95), round(distribution.P99), round(distribution.Max),
+		distribution.Count)
+}
@@ -0,0 +1,193 @@
+package clinic
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+/** metrics **********************************************************/
+
+/**
+ * What a dentist is up to, as far as the metrics can tell
+ */
+type dentistState string
+
+const (
+	dentistAsleep   dentistState = "asleep"
+	dentistTreating dentistState = "treating"
+	dentistIdle     dentistState = "idle"
+	dentistClosed   dentistState = "closed"
+)
+
+var dentistStates = []dentistState{dentistAsleep, dentistTreating, dentistIdle, dentistClosed}
+
+/**
+ * A sink keeping gauges and counters of a run, served over HTTP in the
+ * Prometheus text format, so long runs can be scraped while they go:
+ *
+ *   http.Handle("/metrics", metrics)
+ *
+ * Gauges follow the latest event published, counters add up every event.
+ */
+type Metrics struct {
+	mutex sync.Mutex
+
+	queues   QueueDepths
+	dentists map[int]dentistState
+
+	// Keyed by the name of the patients' priority level
+	treated        map[string]int
+	sentHome       map[string]int
+	balked         map[string]int
+	reneged        map[string]int
+	late           map[string]int
+	noShows        map[string]int
+	agings         int
+	protocolErrors int
+}
+
+/**
+ * Creates metrics with every counter at zero.
+ */
+func NewMetrics() *Metrics {
+	return &Metrics{
+		dentists: map[int]dentistState{},
+		treated:  map[string]int{},
+		sentHome: map[string]int{},
+		balked:   map[string]int{},
+		reneged:  map[string]int{},
+		late:     map[string]int{},
+		noShows:  map[string]int{},
+	}
+}
+
+func (metrics *Metrics) Publish(event Event) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.queues = event.Queues
+
+	switch event.Kind {
+	case FellAsleep:
+		metrics.dentists[event.Actor.ID] = dentistAsleep
+	case StepPerformed:
+		metrics.dentists[event.Actor.ID] = dentistTreating
+	case TreatmentDone:
+		metrics.dentists[event.Actor.ID] = dentistIdle
+	case Closing:
+		if event.Actor.Role == DentistRole {
+			metrics.dentists[event.Actor.ID] = dentistClosed
+		}
+	case PatientLeft:
+		metrics.treated[event.Level]++
+	case PatientSentHome:
+		metrics.sentHome[event.Level]++
+	case PatientBalked:
+		metrics.balked[event.Level]++
+	case PatientReneged:
+		metrics.reneged[event.Level]++
+	case PatientLate:
+		metrics.late[event.Level]++
+	case AppointmentMissed:
+		metrics.noShows[event.Level]++
+	case PatientAged:
+		metrics.agings++
+	case ProtocolFailed:
+		metrics.protocolErrors++
+	}
+}
+
+/**
+ * Writes the metrics in the Prometheus text exposition format.
+ */
+func (metrics *Metrics) ServeHTTP(response http.ResponseWriter, _ *http.Request) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	response.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	fmt.Fprintln(response, "# HELP clinic_queue_length Number of patients queuing in a waiting room.")
+	fmt.Fprintln(response, "# TYPE clinic_queue_length gauge")
+	fmt.Fprintf(response, "clinic_queue_length{room=\"wait\"} %d\n", metrics.queues.Wait)
+	fmt.Fprintf(response, "clinic_queue_length{room=\"hwait\"} %d\n", metrics.queues.HWait)
+	fmt.Fprintf(response, "clinic_queue_length{room=\"lwait\"} %d\n", metrics.queues.LWait)
+
+	fmt.Fprintln(response, "# HELP clinic_level_queue_length Number of patients queuing at a priority level, 0 the least urgent.")
+	fmt.Fprintln(response, "# TYPE clinic_level_queue_length gauge")
+	for level, length := range metrics.queues.Levels {
+		fmt.Fprintf(response, "clinic_level_queue_length{level=\"%d\"} %d\n", level, length)
+	}
+
+	ids := make([]int, 0, len(metrics.dentists))
+	for id := range metrics.dentists {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Fprintln(response, "# HELP clinic_dentist_state Whether a dentist is in a state (1) or not (0).")
+	fmt.Fprintln(response, "# TYPE clinic_dentist_state gauge")
+	for _, id := range ids {
+		for _, state := range dentistStates {
+			value := 0
+			if metrics.dentists[id] == state {
+				value = 1
+			}
+			fmt.Fprintf(response, "clinic_dentist_state{dentist=\"%d\",state=\"%s\"} %d\n", id, state, value)
+		}
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_patients_treated_total Patients who left the clinic treated.")
+	fmt.Fprintln(response, "# TYPE clinic_patients_treated_total counter")
+	for _, priority := range sortedKeys(metrics.treated) {
+		fmt.Fprintf(response, "clinic_patients_treated_total{priority=\"%s\"} %d\n", priority, metrics.treated[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_patients_sent_home_total Patients who left the clinic untreated.")
+	fmt.Fprintln(response, "# TYPE clinic_patients_sent_home_total counter")
+	for _, priority := range sortedKeys(metrics.sentHome) {
+		fmt.Fprintf(response, "clinic_patients_sent_home_total{priority=\"%s\"} %d\n", priority, metrics.sentHome[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_patients_balked_total Patients who left on finding their waiting room full.")
+	fmt.Fprintln(response, "# TYPE clinic_patients_balked_total counter")
+	for _, priority := range sortedKeys(metrics.balked) {
+		fmt.Fprintf(response, "clinic_patients_balked_total{priority=\"%s\"} %d\n", priority, metrics.balked[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_patients_reneged_total Patients who left having run out of patience.")
+	fmt.Fprintln(response, "# TYPE clinic_patients_reneged_total counter")
+	for _, priority := range sortedKeys(metrics.reneged) {
+		fmt.Fprintf(response, "clinic_patients_reneged_total{priority=\"%s\"} %d\n", priority, metrics.reneged[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_appointments_late_total Booked patients who walked in too late to be seen at their slot.")
+	fmt.Fprintln(response, "# TYPE clinic_appointments_late_total counter")
+	for _, priority := range sortedKeys(metrics.late) {
+		fmt.Fprintf(response, "clinic_appointments_late_total{priority=\"%s\"} %d\n", priority, metrics.late[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_appointments_missed_total Booked patients who never showed up.")
+	fmt.Fprintln(response, "# TYPE clinic_appointments_missed_total counter")
+	for _, priority := range sortedKeys(metrics.noShows) {
+		fmt.Fprintf(response, "clinic_appointments_missed_total{priority=\"%s\"} %d\n", priority, metrics.noShows[priority])
+	}
+
+	fmt.Fprintln(response, "# HELP clinic_agings_total Patients moved a priority level up (e.g. lwait to hwait).")
+	fmt.Fprintln(response, "# TYPE clinic_agings_total counter")
+	fmt.Fprintf(response, "clinic_agings_total %d\n", metrics.agings)
+
+	fmt.Fprintln(response, "# HELP clinic_protocol_errors_total Treatment sessions that fell out of sync.")
+	fmt.Fprintln(response, "# TYPE clinic_protocol_errors_total counter")
+	fmt.Fprintf(response, "clinic_protocol_errors_total %d\n", metrics.protocolErrors)
+}
+
+func sortedKeys(counters map[string]int) []string {
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package clinic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, metrics *Metrics) string {
+	t.Helper()
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if kind := response.Header.Get("Content-Type"); !strings.HasPrefix(kind, "text/plain; version=0.0.4") {
+		t.Errorf("metrics served as %q, want the Prometheus text format", kind)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMetricsScrapeARun(t *testing.T) {
+	metrics := NewMetrics()
+	config := Config{
+		Scenario:     PriorityScenario,
+		HWaitSize:    20,
+		LWaitSize:    10,
+		AgingLimit:   500 * time.Millisecond,
+		LowPatients:  10,
+		HighPatients: 20,
+		Clock:        NewVirtualClock(time.Unix(0, 0)),
+		Seed:         42,
+		Sink:         metrics,
+	}
+	if _, err := Run(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t, metrics)
+	for _, line := range []string{
+		"# TYPE clinic_queue_length gauge",
+		`clinic_queue_length{room="hwait"} 0`,
+		`clinic_queue_length{room="lwait"} 0`,
+		`clinic_level_queue_length{level="1"} 0`,
+		`clinic_dentist_state{dentist="1",state="closed"} 1`,
+		`clinic_dentist_state{dentist="1",state="treating"} 0`,
+		"# TYPE clinic_patients_treated_total counter",
+		`clinic_patients_treated_total{priority="high"} 20`,
+		`clinic_patients_treated_total{priority="low"} 10`,
+		"clinic_protocol_errors_total 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("scrape is missing %q:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "clinic_agings_total 0\n") {
+		t.Errorf("scrape counted no aging, with patients waiting well past the aging limit:\n%s", body)
+	}
+}
+
+func TestMetricsCountEvents(t *testing.T) {
+	metrics := NewMetrics()
+	for _, event := range []Event{
+		{Kind: FellAsleep, Actor: Actor{Role: DentistRole, ID: 2}},
+		{Kind: PatientSentHome, Level: "low"},
+		{Kind: PatientSentHome, Level: "low"},
+		{Kind: PatientBalked, Level: "high"},
+		{Kind: PatientReneged, Level: "low"},
+		{Kind: PatientAged},
+		{Kind: ProtocolFailed},
+		{Kind: PatientArrived, Queues: QueueDepths{Wait: 3, HWait: 2, LWait: 1}},
+	} {
+		metrics.Publish(event)
+	}
+
+	body := scrape(t, metrics)
+	for _, line := range []string{
+		`clinic_queue_length{room="wait"} 3`,
+		`clinic_queue_length{room="hwait"} 2`,
+		`clinic_queue_length{room="lwait"} 1`,
+		`clinic_dentist_state{dentist="2",state="asleep"} 1`,
+		`clinic_patients_sent_home_total{priority="low"} 2`,
+		`clinic_patients_balked_total{priority="high"} 1`,
+		`clinic_patients_reneged_total{priority="low"} 1`,
+		"clinic_agings_total 1",
+		"clinic_protocol_errors_total 1",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("scrape is missing %q:\n%s", line, body)
+		}
+	}
+}
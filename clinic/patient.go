@@ -0,0 +1,194 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+/** patient **********************************************************/
+
+/**
+ * The patient. The patient, upon arrival, checks if the dentist
+ * is busy with other patients or sleeping.
+ *   • If the dentist is sleeping, the patient wakes the dentist up and falls
+ *     asleep while being treated. The patient is woken up when the treatment
+ *     is completed, and leaves (i.e., terminates).
+ *   • If the dentist is busy with another patient, the arriving patient goes
+ *     in the waiting room and waits (i.e., sleeps). When the patient is woken
+ *     up, the treatment starts: the patient falls asleep until being woken up
+ *     at the end of the treatment.
+ *
+ * Reports how the visit went, once the patient left.
+ */
+func (clinic *practice) patient(ctx context.Context, treatment *Session) PatientReport {
+	me := Actor{Role: PatientRole, ID: treatment.Patient}
+	visit := PatientReport{
+		ID:        treatment.Patient,
+		Priority:  treatment.Priority,
+		Level:     clinic.level(treatment.Priority),
+		Treatment: treatment.Treatment,
+		Slot:      treatment.Slot,
+		Arrived:   clinic.clock.Now(),
+	}
+	clinic.emit(Event{Kind: PatientArrived, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+	// A booked patient walking in past their slot waits along with the walk-ins
+	if clinic.config.Appointments.late(treatment.Slot, visit.Arrived) {
+		clinic.emit(Event{Kind: PatientLate, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+		visit.Late = true
+	}
+
+	select {
+	// Request treatment (wakes up the dentist if asleep)
+	case clinic.dent <- treatment:
+		clinic.emit(Event{Kind: PatientWokeUp, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+	default:
+		// Dentist is busy, go to the waiting room and wait (i.e. sleep)
+		clinic.checkpoint(me, FoundDentistBusy)
+		switch clinic.queue(ctx, treatment, visit.Arrived) {
+		case balked:
+			clinic.emit(Event{Kind: PatientBalked, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			visit.Balked = true
+			visit.Left = clinic.clock.Now()
+			return visit
+		case reneged:
+			clinic.emit(Event{Kind: PatientReneged, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			visit.Reneged = true
+			visit.Left = clinic.clock.Now()
+			return visit
+		case turnedAway:
+			clinic.emit(Event{Kind: PatientSentHome, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			visit.Left = clinic.clock.Now()
+			return visit
+		}
+		clinic.emit(Event{Kind: PatientQueued, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+	}
+
+	visit.Treated = clinic.receiveTreatment(ctx, treatment, &visit)
+	visit.Left = clinic.clock.Now()
+	return visit
+}
+
+/**
+ * Sits the patient in the waiting room: the one waiting room of part 1, or
+ * the room of their priority level. A patient finding the room full balks
+ * if the clinic says so, and any patient runs out of patience (standing or
+ * seated) the clinic's Patience after they arrived. A booked patient on
+ * time keeps their slot, to be seen at it ahead of the walk-ins.
+ */
+func (clinic *practice) queue(ctx context.Context, patient *Session, arrived time.Time) seating {
+	room := patient.Priority
+	if clinic.config.Scenario == DentistScenario {
+		room = 0
+	}
+
+	entry := queued{patient: patient, expected: expectedDuration(clinic.config.Durations.For(patient.Treatment)), arrived: arrived}
+	if clinic.config.Patience > 0 {
+		entry.leaves = arrived.Add(clinic.config.Patience)
+	}
+	if !clinic.config.Appointments.late(patient.Slot, arrived) {
+		entry.slot = patient.Slot
+	}
+	return clinic.rooms.join(ctx, entry, room, clinic.config.Balking, clinic.clock)
+}
+
+/**
+ * Reneging patients:
+ * Lets seated patients leave their waiting room once their patience runs
+ * out. Patients already called in stay, and those placed in wait by the
+ * assistant leave it on their own (see receiveTreatment).
+ */
+func (clinic *practice) renege(ctx context.Context) {
+	timer := clinic.clock.NewTimer(clinic.config.Patience)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		changed := clinic.rooms.watch()
+
+		// Sleep until the next patient runs out of patience, or the rooms change
+		var due <-chan time.Time
+		if deadline, pending := clinic.rooms.expiry(); pending {
+			timer.Reset(deadline.Sub(clinic.clock.Now()))
+			due = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case now := <-due:
+			clinic.rooms.renege(now)
+		}
+	}
+}
+
+/**
+ * A context done along with ctx, or once the patient's patience runs out
+ * at leaves, on the clinic's clock.
+ */
+func (clinic *practice) patience(ctx context.Context, leaves time.Time) (context.Context, context.CancelFunc) {
+	waiting, leave := context.WithCancel(ctx)
+	timer := clinic.clock.NewTimer(leaves.Sub(clinic.clock.Now()))
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			leave()
+		case <-waiting.Done():
+		}
+	}()
+	return waiting, leave
+}
+
+/**
+ * Emulates receiving a treatment operation, reports false when the
+ * patient was sent home before the treatment was over. The visit
+ * records when the treatment started.
+ */
+func (clinic *practice) receiveTreatment(ctx context.Context, treatment *Session, visit *PatientReport) bool {
+	me := Actor{Role: PatientRole, ID: treatment.Patient}
+
+	// Patients placed in wait (part 3) are out of reach of the rooms,
+	// so they keep an eye on their patience themselves
+	waiting := ctx
+	if clinic.config.Patience > 0 && clinic.config.Scenario == AssistantScenario {
+		var stop context.CancelFunc
+		waiting, stop = clinic.patience(ctx, visit.Arrived.Add(clinic.config.Patience))
+		defer stop()
+	}
+
+	for {
+		// Patient "sleeps" until the next step of the treatment (i.e. gets blocked)
+		step, err := treatment.Await(waiting)
+		if err != nil && visit.Started.IsZero() && ctx.Err() == nil && waiting.Err() != nil {
+			clinic.rooms.leave(treatment)
+		}
+
+		var outOfSync *ProtocolError
+		switch {
+		case errors.As(err, &outOfSync):
+			clinic.emit(Event{Kind: ProtocolFailed, Actor: me, Patient: treatment.Patient, Step: outOfSync.Expected, Err: err.Error()})
+			return false
+		case err != nil && clinic.rooms.reneged(treatment):
+			clinic.emit(Event{Kind: PatientReneged, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			visit.Reneged = true
+			return false
+		case err != nil:
+			clinic.emit(Event{Kind: PatientSentHome, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			return false
+		}
+
+		if step == Start {
+			visit.Started = clinic.clock.Now()
+		}
+
+		if step == Finish {
+			clinic.emit(Event{Kind: PatientLeft, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority})
+			return true
+		}
+
+		// e.g. when qa is received, dentist asks the Patient to smile.
+		clinic.emit(Event{Kind: StepReceived, Actor: me, Patient: treatment.Patient, Priority: treatment.Priority, Step: step})
+	}
+}
@@ -0,0 +1,104 @@
+package clinic
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * Patients walking in 100ms apart from the opening, treated in 3s each,
+ * along with the patients after the first who sat down in a waiting room
+ */
+func impatient(scenario Scenario, patients int, capacity int) (Config, *[]int) {
+	var mutex sync.Mutex
+	queued := []int{}
+	config := Config{
+		Scenario:    scenario,
+		WaitSize:    capacity,
+		HWaitSize:   capacity,
+		LWaitSize:   capacity,
+		LowPatients: patients,
+		// Nobody moves up a room out of the way
+		Policy:    StrictPriority{},
+		Arrivals:  FixedInterval(100 * time.Millisecond),
+		Durations: Durations{Default: Fixed(3 * time.Second)},
+		Clock:     NewVirtualClock(time.Unix(0, 0)),
+		Seed:      1,
+		Sink: SinkFunc(func(event Event) {
+			// Patient 1 sits down for a moment when they walk in before the
+			// dentist fell asleep, to be called in straight away
+			if event.Kind == PatientQueued && event.Patient != 1 {
+				mutex.Lock()
+				defer mutex.Unlock()
+				queued = append(queued, event.Patient)
+			}
+		}),
+	}
+	return config, &queued
+}
+
+func TestPatientsBalkAtAFullRoom(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario} {
+		config, _ := impatient(scenario, 5, 2)
+		config.Balking = true
+		report, err := Run(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The first one is treated right away, the next two sit down
+		if !slices.Equal(report.Balked, []int{4, 5}) || !slices.Equal(report.Treated, []int{1, 2, 3}) {
+			t.Errorf("%s: balked %v and treated %v, want 4 and 5 balking", scenario, report.Balked, report.Treated)
+		}
+	}
+}
+
+func TestPatientsRenegeSeated(t *testing.T) {
+	for _, scenario := range []Scenario{DentistScenario, PriorityScenario, AssistantScenario} {
+		config, queued := impatient(scenario, 3, 5)
+		config.Patience = time.Second
+		report, err := Run(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(report.Reneged, []int{2, 3}) || !slices.Equal(report.Treated, []int{1}) {
+			t.Errorf("%s: reneged %v and treated %v, want 2 and 3 reneging", scenario, report.Reneged, report.Treated)
+		}
+		if !slices.Equal(*queued, []int{2, 3}) {
+			t.Errorf("%s: patients %v sat down, want 2 and 3", scenario, *queued)
+		}
+		for _, patient := range report.Patients[1:] {
+			if waited := patient.Waiting(); waited != config.Patience {
+				t.Errorf("%s: patient %d waited %v, want them to leave after %v", scenario, patient.ID, waited, config.Patience)
+			}
+		}
+		// Nobody is treated after the patients who left
+		if report.Elapsed != 3*time.Second || report.Dentists[0].Treated != 1 {
+			t.Errorf("%s: dentist treated %d patients in %v, want only the first one", scenario, report.Dentists[0].Treated, report.Elapsed)
+		}
+	}
+}
+
+func TestPatientsRenegeStanding(t *testing.T) {
+	// The booked patient takes the only seat ahead of patient 3, who never sits
+	config, queued := impatient(DentistScenario, 3, 1)
+	config.Patience = time.Second
+	config.Appointments = AppointmentBook{Booked: Slots(300*time.Millisecond, 0, 1, 0)}
+	report, err := Run(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(report.Reneged, []int{2, 3, 4}) || !slices.Equal(report.Treated, []int{1}) {
+		t.Errorf("reneged %v and treated %v, want everyone but patient 1 reneging", report.Reneged, report.Treated)
+	}
+	if !slices.Equal(*queued, []int{2, 4}) {
+		t.Errorf("patients %v sat down, want 2 and the booked patient 4", *queued)
+	}
+	if waited := report.Patients[2].Waiting(); waited != config.Patience {
+		t.Errorf("patient 3 stood for %v, want them to leave after %v", waited, config.Patience)
+	}
+}
@@ -0,0 +1,321 @@
+package clinic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/** scheduling policies **********************************************/
+
+/**
+ * A scheduling policy picks which waiting patient is served next, out of
+ * every priority level's waiting room. Pick is only asked when at least one
+ * patient waits, and returns the patient's room and place in that room.
+ *
+ * The waiting rooms are locked while a policy picks, so a policy may
+ * draw from the schedule's random stream but must not block.
+ */
+type SchedulingPolicy interface {
+	Pick(schedule Schedule) (Priority, int)
+}
+
+/**
+ * What a scheduling policy picks the next patient from
+ */
+type Schedule struct {
+	// The patients waiting in each room, the least urgent room first,
+	// and within a room in the order they sat down in it
+	Rooms [][]Waiting
+	// How many patients the policy served out of each room since a room
+	// last started having patients waiting (booked patients aside)
+	Served []int
+	// Draws the policy's random numbers, replayed by the run's seed
+	Random *rand.Rand
+}
+
+/**
+ * A patient waiting to be served, as a scheduling policy sees them
+ */
+type Waiting struct {
+	Patient   int
+	Treatment Treatment
+	// How long the patient's treatment is expected to take
+	Expected time.Duration
+	// When the patient arrived at the clinic, and when they sat down in
+	// their current room (later if they stood for a seat, or were moved a
+	// level up)
+	Arrived time.Time
+	Since   time.Time
+}
+
+/**
+ * Patients are served in the order they arrived, whatever their priority.
+ */
+type FIFO struct{}
+
+func (FIFO) Pick(schedule Schedule) (Priority, int) {
+	return schedule.earliest(func(Waiting) float64 { return 0 })
+}
+
+/**
+ * Patients are served strictly by level, the most urgent first, and in the
+ * order they sat down within a level. Less urgent patients can starve.
+ */
+type StrictPriority struct{}
+
+func (StrictPriority) Pick(schedule Schedule) (Priority, int) {
+	for priority := len(schedule.Rooms) - 1; priority >= 0; priority-- {
+		if len(schedule.Rooms[priority]) > 0 {
+			return Priority(priority), 0
+		}
+	}
+	return 0, 0
+}
+
+/**
+ * Patients are served strictly by level like StrictPriority, but whoever
+ * waited Config.AgingLimit at a level is moved a level up. The policy of
+ * parts 2 and 3, used when a run leaves its policy empty.
+ */
+type PriorityWithAging struct{}
+
+func (PriorityWithAging) Pick(schedule Schedule) (Priority, int) {
+	return StrictPriority{}.Pick(schedule)
+}
+
+/**
+ * Every level gets a share of the patients served in proportion to its
+ * weight, e.g. Weights{1, 3} serves 3 high priority patients for every low
+ * priority one while both rooms have patients waiting. A room owes nothing
+ * for the patients served while it was empty. Weights are listed
+ * least urgent level first; left empty, a level weighs 2×level+1.
+ */
+type WeightedFair struct {
+	Weights []float64
+}
+
+func (policy WeightedFair) Pick(schedule Schedule) (Priority, int) {
+	// Serves the room furthest behind its share, the most urgent on a tie
+	chosen, behind := Priority(0), math.Inf(1)
+	for priority := len(schedule.Rooms) - 1; priority >= 0; priority-- {
+		if len(schedule.Rooms[priority]) == 0 {
+			continue
+		}
+		if share := float64(schedule.Served[priority]+1) / policy.weight(priority); share < behind {
+			chosen, behind = Priority(priority), share
+		}
+	}
+	return chosen, 0
+}
+
+func (policy WeightedFair) weight(priority int) float64 {
+	if priority < len(policy.Weights) && policy.Weights[priority] > 0 {
+		return policy.Weights[priority]
+	}
+	return float64(2*priority + 1)
+}
+
+/**
+ * The patient expected to be done the soonest is served first, whatever
+ * their priority, and the one who arrived first on a tie. Long treatments
+ * can starve.
+ */
+type ShortestFirst struct{}
+
+func (ShortestFirst) Pick(schedule Schedule) (Priority, int) {
+	return schedule.earliest(func(waiting Waiting) float64 { return float64(waiting.Expected) })
+}
+
+/**
+ * Every waiting patient holds tickets, as many as their level's, and a
+ * drawn ticket picks who is served next. Tickets are listed least urgent
+ * level first; left empty, a level holds 2×level+1 tickets.
+ */
+type Lottery struct {
+	Tickets []float64
+}
+
+func (policy Lottery) Pick(schedule Schedule) (Priority, int) {
+	total := 0.0
+	for priority, room := range schedule.Rooms {
+		total += float64(len(room)) * policy.tickets(priority)
+	}
+
+	draw := schedule.Random.Float64() * total
+	for priority, room := range schedule.Rooms {
+		for place := range room {
+			if draw -= policy.tickets(priority); draw < 0 {
+				return Priority(priority), place
+			}
+		}
+	}
+	return StrictPriority{}.Pick(schedule)
+}
+
+func (policy Lottery) tickets(priority int) float64 {
+	if priority < len(policy.Tickets) && policy.Tickets[priority] > 0 {
+		return policy.Tickets[priority]
+	}
+	return float64(2*priority + 1)
+}
+
+/**
+ * The waiting patient ranked the lowest, and who arrived first on a tie
+ */
+func (schedule Schedule) earliest(rank func(Waiting) float64) (Priority, int) {
+	var chosen *Waiting
+	priority, place := Priority(0), 0
+	for level, room := range schedule.Rooms {
+		for i := range room {
+			waiting := &room[i]
+			if chosen == nil || rank(*waiting) < rank(*chosen) ||
+				rank(*waiting) == rank(*chosen) && waiting.Arrived.Before(chosen.Arrived) {
+				chosen, priority, place = waiting, Priority(level), i
+			}
+		}
+	}
+	return priority, place
+}
+
+/**
+ * The policy of a run, and whether it ages patients up a level
+ */
+func (config Config) policy() (SchedulingPolicy, bool) {
+	if config.Policy == nil {
+		return PriorityWithAging{}, true
+	}
+	switch config.Policy.(type) {
+	case PriorityWithAging, *PriorityWithAging:
+		return config.Policy, true
+	default:
+		return config.Policy, false
+	}
+}
+
+/**
+ * How long a treatment is expected to take on average
+ */
+func expectedDuration(model DurationModel) time.Duration {
+	switch model := model.(type) {
+	case Uniform:
+		return model.Min + max(model.Max-model.Min, 0)/2
+	case Exponential:
+		return model.Mean
+	case Normal:
+		return model.Mean
+	case LogNormal:
+		return positive(float64(model.Median) * math.Exp(model.Sigma*model.Sigma/2))
+	case Fixed:
+		return time.Duration(model)
+	default:
+		return 0
+	}
+}
+
+/** parsing **********************************************************/
+
+/**
+ * Parses a scheduling policy from its name and parameters, as in:
+ *   • "fifo" for FIFO
+ *   • "strict" for StrictPriority
+ *   • "aging" for PriorityWithAging
+ *   • "fair:1,3" for WeightedFair{Weights: {1, 3}} (or "fair" for the default weights)
+ *   • "shortest" for ShortestFirst
+ *   • "lottery:1,3" for Lottery{Tickets: {1, 3}} (or "lottery" for the default tickets)
+ */
+func ParseSchedulingPolicy(spec string) (SchedulingPolicy, error) {
+	name, parameters, parameterised := strings.Cut(spec, ":")
+
+	var err error
+	numbers := func() (parsed []float64) {
+		if !parameterised {
+			return nil
+		}
+		for _, argument := range strings.Split(parameters, ",") {
+			number, parseErr := strconv.ParseFloat(strings.TrimSpace(argument), 64)
+			if parseErr != nil {
+				err = parseErr
+				return nil
+			}
+			parsed = append(parsed, number)
+		}
+		return parsed
+	}
+	none := func() bool {
+		if parameterised {
+			err = errors.New("takes no parameters")
+		}
+		return err == nil
+	}
+
+	var policy SchedulingPolicy
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fifo":
+		if none() {
+			policy = FIFO{}
+		}
+	case "strict":
+		if none() {
+			policy = StrictPriority{}
+		}
+	case "aging":
+		if none() {
+			policy = PriorityWithAging{}
+		}
+	case "fair":
+		policy = WeightedFair{Weights: numbers()}
+	case "shortest":
+		if none() {
+			policy = ShortestFirst{}
+		}
+	case "lottery":
+		policy = Lottery{Tickets: numbers()}
+	default:
+		err = errors.New("is unknown")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("clinic: scheduling policy %q %w", spec, err)
+	}
+	return policy, nil
+}
+
+func (FIFO) String() string {
+	return "fifo"
+}
+
+func (StrictPriority) String() string {
+	return "strict"
+}
+
+func (PriorityWithAging) String() string {
+	return "aging"
+}
+
+func (policy WeightedFair) String() string {
+	return withNumbers("fair", policy.Weights)
+}
+
+func (ShortestFirst) String() string {
+	return "shortest"
+}
+
+func (policy Lottery) String() string {
+	return withNumbers("lottery", policy.Tickets)
+}
+
+func withNumbers(name string, numbers []float64) string {
+	if len(numbers) == 0 {
+		return name
+	}
+	formatted := make([]string, len(numbers))
+	for i, number := range numbers {
+		formatted[i] = strconv.FormatFloat(number, 'g', -1, 64)
+	}
+	return name + ":" + strings.Join(formatted, ",")
+}
@@ -0,0 +1,193 @@
+package clinic
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSchedulingPolicy(t *testing.T) {
+	for _, test := range []struct {
+		spec   string
+		policy SchedulingPolicy
+		fails  bool
+	}{
+		{spec: "fifo", policy: FIFO{}},
+		{spec: "Strict", policy: StrictPriority{}},
+		{spec: "aging", policy: PriorityWithAging{}},
+		{spec: "fair", policy: WeightedFair{}},
+		{spec: "fair:1,3", policy: WeightedFair{Weights: []float64{1, 3}}},
+		{spec: "shortest", policy: ShortestFirst{}},
+		{spec: "lottery:1, 2.5", policy: Lottery{Tickets: []float64{1, 2.5}}},
+		{spec: "fifo:1", fails: true},
+		{spec: "fair:1,x", fails: true},
+		{spec: "round-robin", fails: true},
+	} {
+		policy, err := ParseSchedulingPolicy(test.spec)
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("ParseSchedulingPolicy(%q) = %v, want an error", test.spec, policy)
+		case !test.fails && err != nil:
+			t.Errorf("ParseSchedulingPolicy(%q) failed: %v", test.spec, err)
+		case !test.fails && !reflect.DeepEqual(policy, test.policy):
+			t.Errorf("ParseSchedulingPolicy(%q) = %#v, want %#v", test.spec, policy, test.policy)
+		}
+	}
+}
+
+func TestSchedulingPolicyStrings(t *testing.T) {
+	for _, spec := range []string{"fifo", "strict", "aging", "fair", "fair:1,3", "shortest", "lottery:0.5,2"} {
+		policy, err := ParseSchedulingPolicy(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if policy.(interface{ String() string }).String() != spec {
+			t.Errorf("%#v prints as %q, want %q", policy, policy, spec)
+		}
+	}
+}
+
+func TestAgingPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy SchedulingPolicy
+		aging  bool
+	}{
+		{nil, true},
+		{PriorityWithAging{}, true},
+		{&PriorityWithAging{}, true},
+		{StrictPriority{}, false},
+		{WeightedFair{}, false},
+	} {
+		if _, aging := (Config{Policy: test.policy}).policy(); aging != test.aging {
+			t.Errorf("%#v ages patients: %t, want %t", test.policy, aging, test.aging)
+		}
+	}
+}
+
+func TestWeightedFairOwesNothingForEmptyRooms(t *testing.T) {
+	rooms := newWaitingRooms([]Level{{Capacity: 100}, {Capacity: 100}}, WeightedFair{Weights: []float64{1, 3}}, rand.New(rand.NewSource(1)))
+	id := 0
+	sit := func(priority Priority, count int) {
+		for range count {
+			id++
+			entry := queued{patient: NewSession(id, StandardProtocol)}
+			if rooms.join(context.Background(), entry, priority, false, RealClock{}) != seated {
+				t.Fatalf("patient %d was not seated", id)
+			}
+		}
+	}
+	serve := func(count int) (served []Priority) {
+		for range count {
+			_, priority, found := rooms.next(time.Now())
+			if !found {
+				t.Fatal("nobody was waiting")
+			}
+			served = append(served, priority)
+		}
+		return served
+	}
+
+	// A stretch of high priority patients only
+	sit(HighPriority, 20)
+	serve(20)
+
+	// Then both rooms are busy: 3 high priority patients for every low one
+	sit(LowPriority, 10)
+	sit(HighPriority, 30)
+	served := serve(12)
+	high := 0
+	for _, priority := range served {
+		if priority == HighPriority {
+			high++
+		}
+	}
+	if high != 9 {
+		t.Errorf("served %v, want 9 high priority patients out of 12", served)
+	}
+}
+
+func TestPoliciesPick(t *testing.T) {
+	opened := time.Unix(0, 0)
+	waiting := func(patient int, expected time.Duration, arrived time.Duration) Waiting {
+		return Waiting{Patient: patient, Expected: expected * time.Second, Arrived: opened.Add(arrived * time.Second)}
+	}
+	// Patient 3 sat down in lwait first, having stood for a seat since before patient 2 arrived
+	rooms := [][]Waiting{
+		{waiting(3, 2, 1), waiting(4, 1, 3)},
+		{waiting(2, 2, 2), waiting(5, 1, 4)},
+	}
+
+	for _, test := range []struct {
+		policy   SchedulingPolicy
+		priority Priority
+		place    int
+	}{
+		{FIFO{}, 0, 0},
+		{StrictPriority{}, 1, 0},
+		{PriorityWithAging{}, 1, 0},
+		// Patients 4 and 5 are both expected to take a second, 4 arrived first
+		{ShortestFirst{}, 0, 1},
+		{WeightedFair{}, 1, 0},
+	} {
+		schedule := Schedule{Rooms: rooms, Served: []int{0, 0}, Random: rand.New(rand.NewSource(1))}
+		if priority, place := test.policy.Pick(schedule); priority != test.priority || place != test.place {
+			t.Errorf("%v picked patient %d, want patient %d", test.policy, rooms[priority][place].Patient, rooms[test.priority][test.place].Patient)
+		}
+	}
+}
+
+func TestLotteryDrawsByTickets(t *testing.T) {
+	rooms := [][]Waiting{{{Patient: 1}, {Patient: 2}}, {{Patient: 3}}}
+	schedule := Schedule{Rooms: rooms, Served: []int{0, 0}, Random: rand.New(rand.NewSource(1))}
+
+	// The two low priority patients hold a ticket each, the high priority one 3
+	drawn := map[int]int{}
+	const draws = 10000
+	for range draws {
+		priority, place := Lottery{Tickets: []float64{1, 3}}.Pick(schedule)
+		drawn[rooms[priority][place].Patient]++
+	}
+	for patient, tickets := range map[int]float64{1: 1, 2: 1, 3: 3} {
+		if share := float64(drawn[patient]) / draws; share < tickets/5-0.02 || share > tickets/5+0.02 {
+			t.Errorf("patient %d drawn %.1f%% of the time, want %.0f%%", patient, 100*share, 100*tickets/5)
+		}
+	}
+}
+
+func TestFIFOServesStandingPatientsInTheOrderTheyArrived(t *testing.T) {
+	rooms := newWaitingRooms([]Level{{Capacity: 1}, {Capacity: 5}}, FIFO{}, rand.New(rand.NewSource(1)))
+	opened := time.Now()
+	join := func(id int, priority Priority, arrived time.Duration) seating {
+		entry := queued{patient: NewSession(id, StandardProtocol), arrived: opened.Add(arrived)}
+		return rooms.join(context.Background(), entry, priority, false, RealClock{})
+	}
+	serve := func() int {
+		patient, _, found := rooms.next(time.Now())
+		if !found {
+			t.Fatal("nobody was waiting")
+		}
+		return patient.Patient
+	}
+
+	join(1, LowPriority, 0)
+	// Patient 2 stands for the only seat of lwait, before patient 3 walks in
+	seated := make(chan seating)
+	go func() { seated <- join(2, LowPriority, time.Second) }()
+	for standing := 0; standing == 0; {
+		time.Sleep(time.Millisecond)
+		rooms.mutex.Lock()
+		standing = len(rooms.standing[LowPriority])
+		rooms.mutex.Unlock()
+	}
+	join(3, HighPriority, 2*time.Second)
+
+	if served := serve(); served != 1 {
+		t.Fatalf("served patient %d first, want 1", served)
+	}
+	<-seated
+	if served := serve(); served != 2 {
+		t.Errorf("served patient %d after 1, want 2 who arrived before 3", served)
+	}
+}
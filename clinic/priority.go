@@ -0,0 +1,533 @@
+package clinic
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+/** priority levels **********************************************************/
+
+/**
+ * A priority level patients are triaged into, e.g. "emergency". Patients of
+ * each level queue in a waiting room of their own.
+ */
+type Level struct {
+	Name string
+	// Capacity of the level's waiting room, at least one
+	Capacity int
+	// Number of patients coming in at this level
+	Patients int
+}
+
+/**
+ * The priority levels of a run, the least urgent first. Left empty, these
+ * are the two levels of parts 2 and 3: low priority patients queuing in
+ * lwait and high priority patients queuing in hwait.
+ */
+func (config Config) levels() []Level {
+	if len(config.Levels) > 0 {
+		return config.Levels
+	}
+	return []Level{
+		{Name: LowPriority.String(), Capacity: config.LWaitSize, Patients: config.LowPatients},
+		{Name: HighPriority.String(), Capacity: config.HWaitSize, Patients: config.HighPatients},
+	}
+}
+
+/**
+ * The most urgent priority of the run
+ */
+func (clinic *practice) top() Priority {
+	return Priority(len(clinic.levels) - 1)
+}
+
+/**
+ * The name of a priority level
+ */
+func (clinic *practice) level(priority Priority) string {
+	if int(priority) < len(clinic.levels) {
+		return clinic.levels[priority].Name
+	}
+	return priority.String()
+}
+
+/**
+ * The priority level of a run going by a name, e.g. "emergency"
+ */
+func (config Config) priority(name string) (Priority, error) {
+	for priority, level := range config.levels() {
+		if level.Name == name {
+			return Priority(priority), nil
+		}
+	}
+	return 0, fmt.Errorf("clinic: unknown priority level %q", name)
+}
+
+/** priority waiting rooms **********************************************************/
+
+/**
+ * The waiting rooms of every priority level. Unlike a channel, a room can
+ * be looked into, so patients can be aged on how long each of them waited,
+ * and served in whichever order the scheduling policy picks.
+ *
+ * Every change is broadcast by closing the changed channel (and making a
+ * new one), which is how goroutines wait for a room to change.
+ */
+type waitingRooms struct {
+	mutex    sync.Mutex
+	queues   [][]queued
+	capacity []int
+	changed  chan struct{}
+	// Patients standing for a seat in each room, the booked ones on time
+	// first, and the walk-ins in the order they came in
+	standing [][]queued
+	// Patients who ran out of patience in a room, and left
+	left map[*Session]bool
+
+	policy SchedulingPolicy
+	served []int
+	random *rand.Rand
+}
+
+/**
+ * A patient in a waiting room, along with how long their treatment is
+ * expected to take, when they arrived at the clinic and sat down in this
+ * room, when they run out of patience and the slot they are booked for
+ * (never and none when zero)
+ */
+type queued struct {
+	patient  *Session
+	expected time.Duration
+	arrived  time.Time
+	since    time.Time
+	leaves   time.Time
+	slot     time.Time
+}
+
+/**
+ * How a patient's attempt to sit down in a waiting room went
+ */
+type seating int
+
+const (
+	seated seating = iota
+	// The room was full, and the patient left rather than stand for a seat
+	balked
+	// The patient ran out of patience standing for a seat
+	reneged
+	// The clinic closed before a seat was free
+	turnedAway
+)
+
+/**
+ * A patient moved a level up
+ */
+type promotion struct {
+	patient *Session
+	to      Priority
+}
+
+func newWaitingRooms(levels []Level, policy SchedulingPolicy, random *rand.Rand) *waitingRooms {
+	rooms := &waitingRooms{changed: make(chan struct{}), left: map[*Session]bool{}, policy: policy, random: random}
+	for _, level := range levels {
+		rooms.queues = append(rooms.queues, nil)
+		rooms.standing = append(rooms.standing, nil)
+		rooms.capacity = append(rooms.capacity, level.Capacity)
+		rooms.served = append(rooms.served, 0)
+	}
+	return rooms
+}
+
+/**
+ * Closes when any room changes next. Must be called with the mutex held.
+ */
+func (rooms *waitingRooms) notify() {
+	close(rooms.changed)
+	rooms.changed = make(chan struct{})
+}
+
+/**
+ * Closes when any room changes next
+ */
+func (rooms *waitingRooms) watch() <-chan struct{} {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+	return rooms.changed
+}
+
+/**
+ * Queues a patient in a room, standing for a seat if the room is full,
+ * unless they balk. Patients standing for a seat get one in the order they
+ * came in (booked patients on time first), and give up standing once
+ * their patience runs out.
+ */
+func (rooms *waitingRooms) join(ctx context.Context, entry queued, priority Priority, balk bool, clock Clock) seating {
+	var patience <-chan time.Time
+	if !entry.leaves.IsZero() {
+		timer := clock.NewTimer(entry.leaves.Sub(clock.Now()))
+		defer timer.Stop()
+		patience = timer.C()
+	}
+
+	rooms.mutex.Lock()
+	if balk && len(rooms.queues[priority]) >= rooms.capacity[priority] {
+		rooms.mutex.Unlock()
+		return balked
+	}
+	rooms.standing[priority] = line(rooms.standing[priority], entry)
+	for {
+		if rooms.standing[priority][0].patient == entry.patient && len(rooms.queues[priority]) < rooms.capacity[priority] {
+			rooms.standing[priority] = rooms.standing[priority][1:]
+			entry.since = clock.Now()
+			rooms.seat(priority, entry)
+			rooms.notify()
+			rooms.mutex.Unlock()
+			return seated
+		}
+		changed := rooms.changed
+		rooms.mutex.Unlock()
+
+		outcome := seated
+		select {
+		case <-changed:
+		case <-patience:
+			outcome = reneged
+		case <-ctx.Done():
+			outcome = turnedAway
+		}
+
+		rooms.mutex.Lock()
+		if outcome != seated {
+			rooms.leaveLine(priority, entry.patient)
+			rooms.mutex.Unlock()
+			return outcome
+		}
+	}
+}
+
+/**
+ * Lines a patient up for a seat: a booked patient on time goes ahead of
+ * every walk-in, and of the booked patients whose slot comes later.
+ */
+func line(standing []queued, entry queued) []queued {
+	place := len(standing)
+	if !entry.slot.IsZero() {
+		if ahead := slices.IndexFunc(standing, func(other queued) bool {
+			return other.slot.IsZero() || entry.slot.Before(other.slot)
+		}); ahead >= 0 {
+			place = ahead
+		}
+	}
+	return slices.Insert(standing, place, entry)
+}
+
+/**
+ * Takes a patient out of the line standing for a seat. Must be called
+ * with the mutex held.
+ */
+func (rooms *waitingRooms) leaveLine(priority Priority, patient *Session) {
+	rooms.standing[priority] = slices.DeleteFunc(rooms.standing[priority], func(standing queued) bool {
+		return standing.patient == patient
+	})
+	rooms.notify()
+}
+
+/**
+ * Takes the next patient out of the waiting rooms: the booked patient
+ * whose slot came the earliest by now, if any, or else the one the
+ * scheduling policy picks. Reports false when every room is empty.
+ */
+func (rooms *waitingRooms) next(now time.Time) (*Session, Priority, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	if priority, place, booked := rooms.booked(now); booked {
+		return rooms.take(priority, place, false), priority, true
+	}
+	return rooms.pick(func(queued) bool { return true })
+}
+
+/**
+ * Takes the next walk-in out of the waiting rooms, as the scheduling
+ * policy picks them, leaving the booked patients on time to be taken at
+ * their slot (see appointment). Reports false when no walk-in waits.
+ */
+func (rooms *waitingRooms) walkIn() (*Session, Priority, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+	return rooms.pick(func(waiting queued) bool { return waiting.slot.IsZero() })
+}
+
+/**
+ * Takes the booked patient whose slot came the earliest by now out of the
+ * waiting rooms. Reports false when nobody booked is due yet.
+ */
+func (rooms *waitingRooms) appointment(now time.Time) (*Session, Priority, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	if priority, place, booked := rooms.booked(now); booked {
+		return rooms.take(priority, place, false), priority, true
+	}
+	return nil, 0, false
+}
+
+/**
+ * Takes the patient the scheduling policy picks out of the waiting
+ * patients kept. Must be called with the mutex held.
+ */
+func (rooms *waitingRooms) pick(keep func(queued) bool) (*Session, Priority, bool) {
+	schedule := Schedule{Rooms: make([][]Waiting, len(rooms.queues)), Served: slices.Clone(rooms.served), Random: rooms.random}
+	// Where each patient the policy sees sits in their room
+	places := make([][]int, len(rooms.queues))
+	waiting := 0
+	for priority, queue := range rooms.queues {
+		for place, patient := range queue {
+			if !keep(patient) {
+				continue
+			}
+			schedule.Rooms[priority] = append(schedule.Rooms[priority], Waiting{
+				Patient:   patient.patient.Patient,
+				Treatment: patient.patient.Treatment,
+				Expected:  patient.expected,
+				Arrived:   patient.arrived,
+				Since:     patient.since,
+			})
+			places[priority] = append(places[priority], place)
+		}
+		waiting += len(places[priority])
+	}
+	if waiting == 0 {
+		return nil, 0, false
+	}
+
+	// A policy picking an empty seat gets the most urgent patient instead
+	priority, place := rooms.policy.Pick(schedule)
+	if int(priority) < 0 || int(priority) >= len(places) || place < 0 || place >= len(places[priority]) {
+		priority, place = StrictPriority{}.Pick(schedule)
+	}
+
+	return rooms.take(priority, places[priority][place], true), priority, true
+}
+
+/**
+ * Where the booked patient whose slot came the earliest by now sits, the
+ * one who arrived first on a tie. Must be called with the mutex held.
+ */
+func (rooms *waitingRooms) booked(now time.Time) (Priority, int, bool) {
+	var chosen *queued
+	priority, place := Priority(0), 0
+	for level, queue := range rooms.queues {
+		for i := range queue {
+			waiting := &queue[i]
+			if waiting.slot.IsZero() || waiting.slot.After(now) {
+				continue
+			}
+			if chosen == nil || waiting.slot.Before(chosen.slot) ||
+				waiting.slot.Equal(chosen.slot) && waiting.arrived.Before(chosen.arrived) {
+				chosen, priority, place = waiting, Priority(level), i
+			}
+		}
+	}
+	return priority, place, chosen != nil
+}
+
+/**
+ * When the next booked patient sitting in a room is due at their slot
+ */
+func (rooms *waitingRooms) nextSlot() (time.Time, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	var next time.Time
+	for _, queue := range rooms.queues {
+		for _, waiting := range queue {
+			if !waiting.slot.IsZero() && (next.IsZero() || waiting.slot.Before(next)) {
+				next = waiting.slot
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+/**
+ * Takes a patient out of their room to be served, counting them as served
+ * by the scheduling policy if it picked them. Must be called with the
+ * mutex held.
+ */
+func (rooms *waitingRooms) take(priority Priority, place int, picked bool) *Session {
+	queue := rooms.queues[priority]
+	patient := queue[place].patient
+	rooms.queues[priority] = append(queue[:place:place], queue[place+1:]...)
+	if picked {
+		rooms.served[priority]++
+	}
+	rooms.notify()
+	return patient
+}
+
+/**
+ * Seats a patient at the back of a room. A room no longer empty competes
+ * with the others afresh: every room's served count starts over, rather
+ * than the room being owed whatever the others were served while it was
+ * empty. Must be called with the mutex held.
+ */
+func (rooms *waitingRooms) seat(priority Priority, entry queued) {
+	if len(rooms.queues[priority]) == 0 {
+		for room := range rooms.served {
+			rooms.served[room] = 0
+		}
+	}
+	rooms.queues[priority] = append(rooms.queues[priority], entry)
+}
+
+/**
+ * When the next patient is due to move a level up, having waited limit in
+ * their room. Patients only move up into a room with a free seat.
+ */
+func (rooms *waitingRooms) due(limit time.Duration) (time.Time, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	var next time.Time
+	for priority := 0; priority < len(rooms.queues)-1; priority++ {
+		// Rooms are in order of arrival, the first patient waited the longest
+		queue := rooms.queues[priority]
+		if len(queue) == 0 || len(rooms.queues[priority+1]) >= rooms.capacity[priority+1] {
+			continue
+		}
+		if deadline := queue[0].since.Add(limit); next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+/**
+ * Moves every patient who waited limit or more a level up, to the back of
+ * the more urgent room, where their wait starts over. Returns who moved.
+ */
+func (rooms *waitingRooms) promote(now time.Time, limit time.Duration) []promotion {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	var promoted []promotion
+	// The most urgent first, so no patient goes up two levels at once
+	for priority := len(rooms.queues) - 2; priority >= 0; priority-- {
+		for len(rooms.queues[priority]) > 0 && len(rooms.queues[priority+1]) < rooms.capacity[priority+1] {
+			first := rooms.queues[priority][0]
+			if now.Sub(first.since) < limit {
+				break
+			}
+			rooms.queues[priority] = rooms.queues[priority][1:]
+
+			first.since = now
+			rooms.seat(Priority(priority+1), first)
+			promoted = append(promoted, promotion{patient: first.patient, to: Priority(priority + 1)})
+		}
+	}
+	if len(promoted) > 0 {
+		rooms.notify()
+	}
+	return promoted
+}
+
+/**
+ * When the next seated patient runs out of patience
+ */
+func (rooms *waitingRooms) expiry() (time.Time, bool) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	var next time.Time
+	for _, queue := range rooms.queues {
+		for _, waiting := range queue {
+			if !waiting.leaves.IsZero() && (next.IsZero() || waiting.leaves.Before(next)) {
+				next = waiting.leaves
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+/**
+ * Lets every seated patient whose patience ran out by now leave their
+ * room, rejecting their session so they stop waiting on it.
+ */
+func (rooms *waitingRooms) renege(now time.Time) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	for priority, queue := range rooms.queues {
+		rooms.queues[priority] = slices.DeleteFunc(queue, func(waiting queued) bool {
+			if waiting.leaves.IsZero() || now.Before(waiting.leaves) {
+				return false
+			}
+			rooms.left[waiting.patient] = true
+			waiting.patient.Reject()
+			return true
+		})
+	}
+	rooms.notify()
+}
+
+/**
+ * Lets a patient whose patience ran out leave, out of their room if they
+ * still sit in one, rejecting their session so nobody treats them.
+ */
+func (rooms *waitingRooms) leave(patient *Session) {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	for priority, queue := range rooms.queues {
+		rooms.queues[priority] = slices.DeleteFunc(queue, func(waiting queued) bool {
+			return waiting.patient == patient
+		})
+	}
+	rooms.left[patient] = true
+	patient.Reject()
+	rooms.notify()
+}
+
+/**
+ * Whether a patient left having run out of patience
+ */
+func (rooms *waitingRooms) reneged(patient *Session) bool {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+	return rooms.left[patient]
+}
+
+/**
+ * How many patients queue in each room, the least urgent first
+ */
+func (rooms *waitingRooms) lengths() []int {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	lengths := make([]int, len(rooms.queues))
+	for priority, queue := range rooms.queues {
+		lengths[priority] = len(queue)
+	}
+	return lengths
+}
+
+/**
+ * Empties every room by rejecting the patients still queued in them.
+ */
+func (rooms *waitingRooms) dismiss() {
+	rooms.mutex.Lock()
+	defer rooms.mutex.Unlock()
+
+	for priority, queue := range rooms.queues {
+		for _, waiting := range queue {
+			waiting.patient.Reject()
+		}
+		rooms.queues[priority] = nil
+	}
+	rooms.notify()
+}
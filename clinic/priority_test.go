@@ -0,0 +1,147 @@
+package clinic
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * A patient moved a level up, and when from the opening
+ */
+type aged struct {
+	patient int
+	to      Priority
+	at      time.Duration
+}
+
+/**
+ * Runs a clinic on the virtual clock, recording every patient moved a level up
+ */
+func runAging(t *testing.T, config Config) (Report, []aged) {
+	t.Helper()
+	opened := time.Unix(0, 0)
+	var mutex sync.Mutex
+	var moves []aged
+	config.Clock = NewVirtualClock(opened)
+	config.Seed = 1
+	config.Sink = SinkFunc(func(event Event) {
+		if event.Kind == PatientAged {
+			mutex.Lock()
+			defer mutex.Unlock()
+			moves = append(moves, aged{event.Patient, event.Priority, event.Time.Sub(opened)})
+		}
+	})
+
+	report, err := Run(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report, moves
+}
+
+func TestPatientsAgeOnTheirOwnWait(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		urgent int
+		moves  []aged
+	}{
+		{
+			name:   "one level at a time",
+			urgent: 5,
+			moves:  []aged{{1, 1, 2500 * time.Millisecond}, {2, 1, 3 * time.Second}, {1, 2, 4500 * time.Millisecond}, {2, 2, 5 * time.Second}},
+		},
+		{
+			// Patient 2 waits for patient 1 to move out of urgent, and starts over there
+			name:   "only into a free seat",
+			urgent: 1,
+			moves:  []aged{{1, 1, 2500 * time.Millisecond}, {1, 2, 4500 * time.Millisecond}, {2, 1, 4500 * time.Millisecond}, {2, 2, 6500 * time.Millisecond}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// The emergency walks in first and keeps the dentist busy while
+			// the routine patients, walking in 500ms apart, move up every 2s
+			report, moves := runAging(t, Config{
+				Scenario: PriorityScenario,
+				Levels: []Level{
+					{Name: "routine", Capacity: 5, Patients: 2},
+					{Name: "urgent", Capacity: test.urgent},
+					{Name: "emergency", Capacity: 5, Patients: 1},
+				},
+				AgingLimit: 2 * time.Second,
+				Arrivals:   FixedInterval(500 * time.Millisecond),
+				Durations:  Durations{Default: Fixed(10 * time.Second)},
+			})
+
+			if !slices.Equal(moves, test.moves) {
+				t.Errorf("patients moved up %v, want %v", moves, test.moves)
+			}
+			if !slices.Equal(report.Treated, []int{3, 1, 2}) {
+				t.Errorf("treated %v, want the emergency, then 1 and 2", report.Treated)
+			}
+		})
+	}
+}
+
+/**
+ * Four levels, the least urgent first
+ */
+func fourLevels(routine int, emergency int) []Level {
+	return []Level{
+		{Name: "routine", Capacity: 5, Patients: routine},
+		{Name: "urgent", Capacity: 5},
+		{Name: "serious", Capacity: 5},
+		{Name: "emergency", Capacity: 5, Patients: emergency},
+	}
+}
+
+func TestLevelsAreServedFromTheTopDown(t *testing.T) {
+	// Three routine walk-ins, then a patient at every level above, walking
+	// in late for their appointment so they wait along with the walk-ins
+	report, err := Run(context.Background(), Config{
+		Scenario:  PriorityScenario,
+		Levels:    fourLevels(3, 0),
+		Policy:    StrictPriority{},
+		Arrivals:  FixedInterval(100 * time.Millisecond),
+		Durations: Durations{Default: Fixed(time.Second)},
+		Appointments: AppointmentBook{
+			Booked:   []Appointment{{Slot: 300 * time.Millisecond, Priority: 1}, {Slot: 400 * time.Millisecond, Priority: 2}, {Slot: 500 * time.Millisecond, Priority: 3}},
+			Lateness: Fixed(time.Millisecond),
+		},
+		Clock: NewVirtualClock(time.Unix(0, 0)),
+		Seed:  1,
+		Sink:  Sinks{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(report.Late, []int{4, 5, 6}) {
+		t.Fatalf("late patients %v, want every booked patient", report.Late)
+	}
+	// The first walk-in is already in the chair when the others walk in
+	if want := []int{1, 6, 5, 4, 2, 3}; !slices.Equal(report.Treated, want) {
+		t.Errorf("treated %v, want %v", report.Treated, want)
+	}
+}
+
+func TestPatientsAgeThroughEveryLevel(t *testing.T) {
+	// The emergency keeps the dentist busy while the routine patient climbs up
+	report, moves := runAging(t, Config{
+		Scenario:   PriorityScenario,
+		Levels:     fourLevels(1, 1),
+		AgingLimit: time.Second,
+		Arrivals:   FixedInterval(500 * time.Millisecond),
+		Durations:  Durations{Default: Fixed(10 * time.Second)},
+	})
+
+	want := []aged{{1, 1, 1500 * time.Millisecond}, {1, 2, 2500 * time.Millisecond}, {1, 3, 3500 * time.Millisecond}}
+	if !slices.Equal(moves, want) {
+		t.Errorf("patients moved up %v, want %v", moves, want)
+	}
+	if !slices.Equal(report.Treated, []int{2, 1}) {
+		t.Errorf("treated %v, want the emergency first", report.Treated)
+	}
+}
@@ -0,0 +1,27 @@
+package clinic
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+/** randomness **********************************************************/
+
+/**
+ * Picks a seed for runs that did not ask for one.
+ */
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+/**
+ * Every random stream of a run derives from the run's seed, so the same
+ * seed replays the same run. Each purpose draws from a stream of its own,
+ * so drawing more of one (e.g. treatments) never shifts the others.
+ */
+func stream(seed int64, purpose string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(purpose))
+	return rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+}
@@ -0,0 +1,297 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/** treatment protocol **********************************************/
+
+/**
+ * The steps the dentist and patient go through during a treatment. Some
+ * treatments prepare the patient before the work itself, numbing their
+ * mouth (Anaesthetic) or taking an X-ray of their teeth (XRay).
+ */
+type TreatmentStep int
+
+const (
+	Start TreatmentStep = iota
+	QA
+	Finish
+	Anaesthetic
+	XRay
+)
+
+var treatmentStepNames = map[TreatmentStep]string{
+	Start:       "start",
+	QA:          "qa",
+	Finish:      "finish",
+	Anaesthetic: "anaesthetic",
+	XRay:        "x-ray",
+}
+
+/**
+ * Whether the step prepares the patient for the work itself
+ */
+func (step TreatmentStep) preparation() bool {
+	return step == Start || step == Anaesthetic || step == XRay
+}
+
+func (step TreatmentStep) String() string {
+	if name, known := treatmentStepNames[step]; known {
+		return name
+	}
+	return fmt.Sprintf("step(%d)", int(step))
+}
+
+/**
+ * Finds a treatment step by name, e.g. "qa"
+ */
+func ParseTreatmentStep(name string) (TreatmentStep, error) {
+	for step, known := range treatmentStepNames {
+		if known == name {
+			return step, nil
+		}
+	}
+	return 0, fmt.Errorf("clinic: unknown treatment step %q", name)
+}
+
+/**
+ * A protocol lists the steps of a treatment in the order the dentist walks
+ * the patient through them. It always opens with Start and closes with
+ * Finish, the one step that is a handshake: the patient asks to leave and
+ * the dentist lets them go. Any step in between is sent by the dentist.
+ */
+type Protocol []TreatmentStep
+
+/**
+ * The protocol every treatment followed so far: start → qa → finish
+ */
+var StandardProtocol = Protocol{Start, QA, Finish}
+
+/**
+ * The protocol with extra steps inserted right after Start, e.g. the
+ * preparation a kind of treatment goes through before the work itself.
+ */
+func (protocol Protocol) With(steps ...TreatmentStep) Protocol {
+	if len(steps) == 0 || len(protocol) == 0 {
+		return protocol
+	}
+	with := make(Protocol, 0, len(protocol)+len(steps))
+	with = append(with, protocol[0])
+	with = append(with, steps...)
+	return append(with, protocol[1:]...)
+}
+
+/**
+ * Checks a protocol opens with Start, closes with Finish and
+ * does not use either of them in between.
+ */
+func (protocol Protocol) Validate() error {
+	if len(protocol) < 2 || protocol[0] != Start || protocol[len(protocol)-1] != Finish {
+		return fmt.Errorf("clinic: protocol %v must open with %s and close with %s", protocol, Start, Finish)
+	}
+	for _, step := range protocol[1 : len(protocol)-1] {
+		if step == Start || step == Finish {
+			return fmt.Errorf("clinic: protocol %v repeats %s", protocol, step)
+		}
+	}
+	return nil
+}
+
+/** errors **********************************************************/
+
+/**
+ * Returned to both sides once a patient is sent home (or walks out)
+ * before the treatment is over.
+ */
+var ErrRejected = errors.New("clinic: patient left without a treatment")
+
+/**
+ * Returned when a step is attempted after the treatment is over.
+ */
+var ErrTreatmentOver = errors.New("clinic: treatment is already over")
+
+/**
+ * Returned when one side of a session receives or attempts a step out of
+ * the protocol's order. The session is abandoned when this happens.
+ */
+type ProtocolError struct {
+	Expected TreatmentStep
+	Received TreatmentStep
+}
+
+func (err *ProtocolError) Error() string {
+	return fmt.Sprintf("clinic: treatment out of sync, expected %s but got %s", err.Expected, err.Received)
+}
+
+/** session **********************************************************/
+
+/**
+ * A session is an appointed treatment between a dentist and a patient. It
+ * replaces the bare `chan int` the two used to exchange steps over, and
+ * keeps track of where each side is in the protocol so steps out of order
+ * surface as errors.
+ *
+ * Only the first step can be interrupted by the clinic closing, once the
+ * patient is in the chair the treatment is seen through.
+ */
+type Session struct {
+	Patient int
+	// Which waiting room the patient came in through
+	Priority Priority
+	// What the patient came in for, and how long the treatment is going to take
+	Treatment Treatment
+	Duration  time.Duration
+	// When the patient is booked for, zero for walk-ins
+	Slot time.Time
+
+	protocol Protocol
+	steps    chan TreatmentStep
+	over     chan struct{}
+	abandon  sync.Once
+
+	// Position of each side in the protocol, each only touched by its own side
+	dentist int
+	patient int
+}
+
+/**
+ * Appoints a new treatment session for a patient.
+ */
+func NewSession(patient int, protocol Protocol) *Session {
+	return &Session{
+		Patient:  patient,
+		protocol: protocol,
+		steps:    make(chan TreatmentStep),
+		over:     make(chan struct{}),
+	}
+}
+
+/**
+ * The steps of the treatment, in order
+ */
+func (session *Session) Protocol() Protocol {
+	return session.protocol
+}
+
+/**
+ * Sends the patient home without a treatment. It is safe to reject a
+ * session more than once, and from either side.
+ */
+func (session *Session) Reject() {
+	session.abandon.Do(func() {
+		close(session.over)
+	})
+}
+
+/**
+ * Performs the dentist's next step of the protocol. For Finish, the dentist
+ * waits for the patient to ask to leave, and acknowledges it.
+ */
+func (session *Session) Perform(ctx context.Context, step TreatmentStep) error {
+	if session.dentist >= len(session.protocol) {
+		return ErrTreatmentOver
+	}
+	if expected := session.protocol[session.dentist]; step != expected {
+		return &ProtocolError{Expected: expected, Received: step}
+	}
+
+	if step == Finish {
+		request, err := session.receive(nil)
+		if err != nil {
+			return err
+		}
+		if request != Finish {
+			session.Reject()
+			return &ProtocolError{Expected: Finish, Received: request}
+		}
+	}
+
+	if err := session.send(step, session.closing(ctx, session.dentist)); err != nil {
+		return err
+	}
+
+	session.dentist++
+	return nil
+}
+
+/**
+ * Waits for the patient's next step of the protocol. For Finish, the
+ * patient asks to leave first, and waits for the dentist to agree.
+ */
+func (session *Session) Await(ctx context.Context) (TreatmentStep, error) {
+	if session.patient >= len(session.protocol) {
+		return Finish, ErrTreatmentOver
+	}
+
+	expected := session.protocol[session.patient]
+	if expected == Finish {
+		if err := session.send(Finish, nil); err != nil {
+			return expected, err
+		}
+	}
+
+	step, err := session.receive(session.closing(ctx, session.patient))
+	if err != nil {
+		return expected, err
+	}
+	if step != expected {
+		session.Reject()
+		return step, &ProtocolError{Expected: expected, Received: step}
+	}
+
+	session.patient++
+	return step, nil
+}
+
+/**
+ * The clinic closing only interrupts a session before it starts
+ */
+func (session *Session) closing(ctx context.Context, position int) <-chan struct{} {
+	if position > 0 {
+		return nil
+	}
+	return ctx.Done()
+}
+
+func (session *Session) send(step TreatmentStep, closing <-chan struct{}) error {
+	select {
+	case session.steps <- step:
+		return nil
+	case <-session.over:
+		return ErrRejected
+	case <-closing:
+		session.Reject()
+		return ErrRejected
+	}
+}
+
+func (session *Session) receive(closing <-chan struct{}) (TreatmentStep, error) {
+	select {
+	case step := <-session.steps:
+		return step, nil
+	case <-session.over:
+		return Finish, ErrRejected
+	case <-closing:
+		session.Reject()
+		return Finish, ErrRejected
+	}
+}
+
+/**
+ * Empties a waiting room by rejecting every patient still queued in it.
+ */
+func Dismiss(waitingRoom <-chan *Session) {
+	for {
+		select {
+		case session := <-waitingRoom:
+			session.Reject()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestProtocolValidate(t *testing.T) {
+	for _, test := range []struct {
+		protocol Protocol
+		valid    bool
+	}{
+		{StandardProtocol, true},
+		{Protocol{Start, Finish}, true},
+		{Protocol{Start, XRay, Anaesthetic, QA, XRay, Finish}, true},
+		{nil, false},
+		{Protocol{Start}, false},
+		{Protocol{QA, Finish}, false},
+		{Protocol{Start, QA}, false},
+		{Protocol{Start, Start, Finish}, false},
+		{Protocol{Start, Finish, Finish}, false},
+		{Protocol{Start, Finish, QA, Finish}, false},
+	} {
+		if err := test.protocol.Validate(); (err == nil) != test.valid {
+			t.Errorf("%v.Validate() = %v, want valid: %t", test.protocol, err, test.valid)
+		}
+	}
+}
+
+func TestProtocolWith(t *testing.T) {
+	with := StandardProtocol.With(XRay, Anaesthetic)
+	if want := (Protocol{Start, XRay, Anaesthetic, QA, Finish}); !slices.Equal(with, want) {
+		t.Errorf("%v.With(%s, %s) = %v, want %v", StandardProtocol, XRay, Anaesthetic, with, want)
+	}
+	if !slices.Equal(StandardProtocol, Protocol{Start, QA, Finish}) {
+		t.Errorf("With changed the protocol it was called on, %v", StandardProtocol)
+	}
+}
+
+func TestSessionWalksThroughTheProtocol(t *testing.T) {
+	session := NewSession(1, Protocol{Start, XRay, QA, Finish})
+	performed := make(chan error, 1)
+	go func() {
+		for _, step := range session.Protocol() {
+			if err := session.Perform(context.Background(), step); err != nil {
+				performed <- err
+				return
+			}
+		}
+		performed <- nil
+	}()
+
+	for _, want := range session.Protocol() {
+		step, err := session.Await(context.Background())
+		if err != nil || step != want {
+			t.Fatalf("patient received %s, %v, want %s", step, err, want)
+		}
+	}
+	if err := <-performed; err != nil {
+		t.Fatalf("dentist failed: %v", err)
+	}
+
+	if err := session.Perform(context.Background(), Finish); !errors.Is(err, ErrTreatmentOver) {
+		t.Errorf("dentist performing past the end got %v, want %v", err, ErrTreatmentOver)
+	}
+	if _, err := session.Await(context.Background()); !errors.Is(err, ErrTreatmentOver) {
+		t.Errorf("patient awaiting past the end got %v, want %v", err, ErrTreatmentOver)
+	}
+}
+
+func TestSessionOutOfOrder(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// Runs one side out of order, against the other side done right
+		run      func(session *Session) error
+		expected TreatmentStep
+		received TreatmentStep
+	}{
+		{
+			name:     "dentist skips start",
+			run:      func(session *Session) error { return session.Perform(context.Background(), QA) },
+			expected: Start,
+			received: QA,
+		},
+		{
+			name: "dentist repeats start",
+			run: func(session *Session) error {
+				go session.Await(context.Background())
+				if err := session.Perform(context.Background(), Start); err != nil {
+					return err
+				}
+				return session.Perform(context.Background(), Start)
+			},
+			expected: QA,
+			received: Start,
+		},
+		{
+			name: "patient receives qa first",
+			run: func(session *Session) error {
+				go func() { session.steps <- QA }()
+				_, err := session.Await(context.Background())
+				return err
+			},
+			expected: Start,
+			received: QA,
+		},
+		{
+			name: "patient asks to leave with the wrong step",
+			run: func(session *Session) error {
+				session.dentist = len(session.protocol) - 1
+				go func() { session.steps <- QA }()
+				return session.Perform(context.Background(), Finish)
+			},
+			expected: Finish,
+			received: QA,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			session := NewSession(1, StandardProtocol)
+			// Lets the side done right go once the test is over
+			defer session.Reject()
+			err := test.run(session)
+
+			var outOfSync *ProtocolError
+			if !errors.As(err, &outOfSync) {
+				t.Fatalf("got %v, want a protocol error", err)
+			}
+			if outOfSync.Expected != test.expected || outOfSync.Received != test.received {
+				t.Errorf("got %v, want %s expected and %s received", err, test.expected, test.received)
+			}
+		})
+	}
+}
+
+func TestSessionRejected(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		reject func(session *Session)
+	}{
+		{"by the dentist", func(session *Session) { session.Reject() }},
+		{"twice by the dentist", func(session *Session) { session.Reject(); session.Reject() }},
+		{"by both sides", func(session *Session) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				session.Reject()
+			}()
+			session.Reject()
+			<-done
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			session := NewSession(1, StandardProtocol)
+			test.reject(session)
+
+			if err := session.Perform(context.Background(), Start); !errors.Is(err, ErrRejected) {
+				t.Errorf("dentist got %v, want %v", err, ErrRejected)
+			}
+			if _, err := session.Await(context.Background()); !errors.Is(err, ErrRejected) {
+				t.Errorf("patient got %v, want %v", err, ErrRejected)
+			}
+		})
+	}
+}
+
+func TestSessionClosingOnlyInterruptsTheFirstStep(t *testing.T) {
+	closed, closeClinic := context.WithCancel(context.Background())
+	closeClinic()
+
+	// Before the treatment starts, either side gives up as the clinic closes
+	waiting := NewSession(1, StandardProtocol)
+	if _, err := waiting.Await(closed); !errors.Is(err, ErrRejected) {
+		t.Errorf("patient waiting for a closed clinic got %v, want %v", err, ErrRejected)
+	}
+	if err := NewSession(2, StandardProtocol).Perform(closed, Start); !errors.Is(err, ErrRejected) {
+		t.Errorf("dentist starting in a closed clinic got %v, want %v", err, ErrRejected)
+	}
+
+	// Once it started, the treatment is seen through
+	started := NewSession(3, StandardProtocol)
+	performed := make(chan error, 1)
+	go func() {
+		if err := started.Perform(context.Background(), Start); err != nil {
+			performed <- err
+			return
+		}
+		for _, step := range started.Protocol()[1:] {
+			if err := started.Perform(closed, step); err != nil {
+				performed <- err
+				return
+			}
+		}
+		performed <- nil
+	}()
+	if _, err := started.Await(context.Background()); err != nil {
+		t.Fatalf("patient missed the start: %v", err)
+	}
+	for range started.Protocol()[1:] {
+		if _, err := started.Await(closed); err != nil {
+			t.Fatalf("patient in the chair was interrupted: %v", err)
+		}
+	}
+	if err := <-performed; err != nil {
+		t.Errorf("dentist was interrupted: %v", err)
+	}
+}
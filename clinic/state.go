@@ -0,0 +1,21 @@
+/**
+ * Package clinic holds the building blocks shared by the dental clinic
+ * scenarios (the dentist, the priority queues and the assistant).
+ */
+package clinic
+
+/** state **********************************************************/
+
+/**
+ * Signal is used to indicate the dentist is "ready" to treat patients
+ */
+const Signal = true
+
+/**
+ * A function to enforce consuming expected channel values
+ */
+func Accept(operation interface{}, expected interface{}, msg string) {
+	if operation != expected {
+		panic(msg)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/u-ways/go-channels/clinic"
+	"github.com/u-ways/go-channels/internal/cpu"
+)
+
+/** idle benchmark **********************************************************/
+
+/**
+ * Measures how much CPU each scenario burns while its clinic is open but
+ * nobody is there yet: the staff should all be asleep, not polling.
+ *
+ *   clinic bench [-idle 3s] [-dentists 2] [-assistants 2]
+ *
+ * Every scenario opens on the real clock, its single patient walking in
+ * once the clinic has been idle for -idle.
+ */
+func bench(arguments []string) {
+	idle := 3 * time.Second
+	dentists := 1
+	assistants := 1
+
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	flags.DurationVar(&idle, "idle", idle, "how long each clinic stays open without patients")
+	flags.IntVar(&dentists, "dentists", dentists, "number of dentists waiting for patients")
+	flags.IntVar(&assistants, "assistants", assistants, "number of assistants waiting for patients (assistant scenario)")
+	flags.Parse(arguments)
+
+	if _, measured := cpu.Time(); !measured {
+		fmt.Fprintln(os.Stderr, "clinic: CPU time cannot be measured on this platform")
+		os.Exit(2)
+	}
+
+	for _, scenario := range scenarios {
+		config := scenario.config
+		config.Dentists = dentists
+		config.Assistants = assistants
+		config.Levels = nil
+		config.LowPatients, config.HighPatients = 1, 0
+		config.Opening = idle
+		config.Durations = clinic.Durations{Default: clinic.Fixed(0)}
+		config.Seed = 1
+		config.Sink = clinic.Sinks{}
+
+		before, _ := cpu.Time()
+		began := time.Now()
+		if _, err := clinic.Run(context.Background(), config); err != nil {
+			log.Fatal(err)
+		}
+		elapsed := time.Since(began)
+		after, _ := cpu.Time()
+
+		used := after - before
+		fmt.Printf("%-10s idle %v, used %v of CPU (%.2f%% of one core)\n",
+			scenario.name, elapsed.Round(time.Millisecond), used.Round(time.Microsecond), 100*used.Seconds()/elapsed.Seconds())
+	}
+}
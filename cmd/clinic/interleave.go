@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/u-ways/go-channels/clinic"
+)
+
+/** interleaving harness **********************************************************/
+
+/**
+ * Checks no patient is ever stranded in a waiting room while a dentist
+ * sleeps, by running every scenario under every interleaving of its
+ * wake-up checkpoints (see clinic.CheckWakeUps):
+ *
+ *   clinic interleave [-dentists 2] [-patients 3] [-assistants 1]
+ *
+ * Exits with status 1 if any interleaving strands a patient.
+ */
+func interleave(arguments []string) {
+	dentists := 1
+	patients := 3
+	assistants := 1
+	limit := 100000
+	verbose := false
+
+	flags := flag.NewFlagSet("interleave", flag.ExitOnError)
+	flags.IntVar(&dentists, "dentists", dentists, "number of dentists")
+	flags.IntVar(&patients, "patients", patients, "number of patients, split between the priority levels")
+	flags.IntVar(&assistants, "assistants", assistants, "number of assistants (assistant scenario)")
+	flags.IntVar(&limit, "limit", limit, "most interleavings to run per scenario, over every workload")
+	flags.BoolVar(&verbose, "v", verbose, "print every stranding interleaving, not just the first")
+	flags.Parse(arguments)
+
+	stranded := false
+	for _, scenario := range scenarios {
+		config := scenario.config
+		config.Dentists = dentists
+		config.Assistants = assistants
+		config.Levels = nil
+		config.HighPatients = patients / 2
+		config.LowPatients = patients - config.HighPatients
+		if config.Scenario == clinic.DentistScenario {
+			config.HighPatients, config.LowPatients = 0, patients
+		}
+		config.WaitSize = max(config.WaitSize, patients)
+		config.HWaitSize = max(config.HWaitSize, patients)
+		config.LWaitSize = max(config.LWaitSize, patients)
+
+		check, err := clinic.CheckWakeUps(context.Background(), config, limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		coverage := "every interleaving"
+		if !check.Exhaustive {
+			coverage = "stopped at -limit"
+		}
+		fmt.Printf("%-10s %d interleavings (%s), %d stranding a patient\n",
+			scenario.name, check.Explored, coverage, len(check.Stranded))
+
+		for i, interleaving := range check.Stranded {
+			if i > 0 && !verbose {
+				break
+			}
+			stranded = true
+			fmt.Printf("  patients %v stranded (%s) after:\n", check.Patients[i], check.Workloads[i])
+			for _, pause := range interleaving {
+				fmt.Printf("    %s\n", pause)
+			}
+		}
+	}
+
+	if stranded {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,481 @@
+/**
+ * The clinic command runs any of the clinic scenarios, with every one of
+ * their settings exposed as a flag:
+ *
+ *   clinic dentist   [flags]   (part 1)
+ *   clinic priority  [flags]   (part 2)
+ *   clinic assistant [flags]   (part 3)
+ *
+ * or any run described by a scenario file (see clinic.ScenarioFile):
+ *
+ *   clinic run scenarios/part2.yaml [flags]
+ *
+ * or measure the CPU an idle clinic uses (see bench), or check no patient
+ * is ever stranded by a sleeping dentist (see interleave):
+ *
+ *   clinic bench [flags]
+ *   clinic interleave [flags]
+ *
+ * Run `clinic <scenario> -h` to list the flags of a scenario.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-ways/go-channels/clinic"
+)
+
+/** scenarios **********************************************************/
+
+/**
+ * A scenario the command can run, along with its default settings
+ * (the constants the scenario's original main used).
+ */
+type scenario struct {
+	name    string
+	summary string
+	config  clinic.Config
+	timeout time.Duration
+}
+
+var scenarios = []scenario{
+	{
+		name:    "dentist",
+		summary: "patients queue in a single waiting room (part 1)",
+		config: clinic.Config{
+			Scenario:        clinic.DentistScenario,
+			WaitSize:        5,
+			LowPatients:     10,
+			Opening:         2 * time.Second,
+			ArrivalInterval: time.Second,
+		},
+		timeout: (2 + 10 + 3*10) * time.Second,
+	},
+	{
+		name:    "priority",
+		summary: "the dentist serves high priority patients first (part 2)",
+		config: clinic.Config{
+			Scenario:     clinic.PriorityScenario,
+			HWaitSize:    50,
+			LWaitSize:    5,
+			AgingLimit:   3000 * time.Millisecond,
+			LowPatients:  10,
+			HighPatients: 20,
+		},
+		timeout: 5 * (20 + 10) * time.Second,
+	},
+	{
+		name:    "assistant",
+		summary: "an assistant triages patients into the dentist's waiting room (part 3)",
+		config: clinic.Config{
+			Scenario:     clinic.AssistantScenario,
+			WaitSize:     15,
+			HWaitSize:    20,
+			LWaitSize:    10,
+			AgingLimit:   500 * time.Millisecond,
+			LowPatients:  10,
+			HighPatients: 20,
+		},
+		timeout: 5 * (20 + 10) * time.Second,
+	},
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Main Method                                                                                             //
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func main() {
+	log.SetFlags(log.Ltime)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "bench":
+		bench(os.Args[2:])
+		return
+	case "interleave":
+		interleave(os.Args[2:])
+		return
+	}
+
+	chosen, found := find(os.Args[1])
+	arguments := os.Args[2:]
+	if os.Args[1] == "run" && len(arguments) > 0 {
+		var err error
+		if chosen, err = load(arguments[0]); err != nil {
+			log.Fatal(err)
+		}
+		found, arguments = true, arguments[1:]
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "clinic: unknown scenario %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	config := chosen.config
+	maxThreads := 5
+	timeout := chosen.timeout
+	virtual := false
+	durations := durationFlag{&config.Durations.Default}
+	events := "console"
+	eventsFile := "-"
+	metricsAddress := ""
+	dashboard := false
+
+	flags := flag.NewFlagSet(chosen.name, flag.ExitOnError)
+	flags.IntVar(&maxThreads, "threads", maxThreads, "maximum number of OS threads executing goroutines (GOMAXPROCS)")
+	flags.IntVar(&config.Dentists, "dentists", max(config.Dentists, 1), "number of dentists working off the same waiting room")
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "seed driving the randomness of the run (0 picks a new one)")
+	flags.Var(durations, "duration", "duration model of every treatment, e.g. exponential:2s, normal:2s,500ms, lognormal:2s,0.5 or fixed:2s (default uniform:1s,3s for a check-up, each treatment type's own otherwise)")
+	flags.Var(treatmentsFlag{&config.Treatments}, "treatments", "what patients come in for, as treatment:weight pairs, e.g. checkup:3,cleaning:2,filling:1,extraction:0.5,root-canal:0.5 (default checkup)")
+	flags.Var(arrivalFlag{&config.Arrivals}, "arrivals", "patient arrival process, e.g. fixed:1s, poisson:0.5 (patients per second), batch:5,10s or replay:arrivals.txt")
+	flags.BoolVar(&config.Balking, "balk", config.Balking, "patients finding their waiting room full leave right away rather than stand for a seat")
+	flags.DurationVar(&config.Patience, "patience", config.Patience, "how long after arriving patients give up waiting and leave untreated (0 for never)")
+	flags.Var(appointmentsFlag{&config.Appointments.Booked}, "appointments", "patients booked ahead at the least urgent level, as count,interval[,first], e.g. 6,10s (the first slot one interval after the opening unless given)")
+	flags.DurationVar(&config.Appointments.Tolerance, "tolerance", config.Appointments.Tolerance, "how late a booked patient can walk in and still be seen at their slot, ahead of the walk-ins")
+	flags.DurationVar(&config.Appointments.Early, "early", config.Appointments.Early, "how long before their slot booked patients walk in")
+	flags.Var(durationFlag{&config.Appointments.Lateness}, "lateness", "how late booked patients walk in on top of that, as a duration model, e.g. exponential:2s (default never)")
+	flags.Float64Var(&config.Appointments.NoShows, "no-shows", config.Appointments.NoShows, "chance a booked patient never shows up, between 0 and 1")
+	flags.BoolVar(&virtual, "virtual", false, "run on a virtual clock, finishing as fast as the goroutines can go")
+	flags.DurationVar(&timeout, "timeout", timeout, "closes the clinic after this long even if patients are still there (0 for never)")
+	flags.StringVar(&events, "events", events, "how events are written: console (coloured lines) or jsonl (one JSON object per line)")
+	flags.StringVar(&eventsFile, "events-file", eventsFile, "file the jsonl events are written to (- for stdout)")
+	flags.BoolVar(&dashboard, "dashboard", false, "draw a live full screen dashboard of the run instead of the console lines")
+	flags.StringVar(&metricsAddress, "metrics", metricsAddress, "address serving Prometheus metrics on /metrics while the clinic runs, e.g. localhost:9090")
+
+	switch config.Scenario {
+	case clinic.DentistScenario:
+		flags.IntVar(&config.LowPatients, "patients", config.LowPatients, "number of patients")
+		flags.IntVar(&config.WaitSize, "wait", config.WaitSize, "capacity of the waiting room")
+		flags.DurationVar(&config.Opening, "opening", config.Opening, "delay before the first patient arrives")
+		flags.DurationVar(&config.ArrivalInterval, "interval", config.ArrivalInterval, "delay between two patient arrivals")
+	case clinic.AssistantScenario:
+		flags.IntVar(&config.Assistants, "assistants", max(config.Assistants, 1), "number of assistants triaging patients")
+		flags.IntVar(&config.WaitSize, "wait", config.WaitSize, "capacity of the dentist's waiting room (wait)")
+		fallthrough
+	case clinic.PriorityScenario:
+		flags.IntVar(&config.HWaitSize, "hwait", config.HWaitSize, "capacity of the high priority waiting room (hwait)")
+		flags.IntVar(&config.LWaitSize, "lwait", config.LWaitSize, "capacity of the low priority waiting room (lwait)")
+		flags.Var(policyFlag{&config.Policy}, "policy", "which waiting patient is served next: fifo, strict, aging, fair:1,3 (weights, least urgent first), shortest or lottery:1,3 (tickets) (default aging)")
+		flags.DurationVar(&config.AgingLimit, "aging", config.AgingLimit, "how long a patient waits at a priority level before being moved a level up")
+		flags.IntVar(&config.LowPatients, "low", config.LowPatients, "number of low priority patients")
+		flags.IntVar(&config.HighPatients, "high", config.HighPatients, "number of high priority patients")
+		flags.Var(levelsFlag{&config.Levels}, "levels", "priority levels replacing hwait/lwait, least urgent first, as name:capacity:patients, e.g. routine:10:8,urgent:5:4,emergency:2:1")
+	}
+
+	flags.Parse(arguments)
+
+	runtime.GOMAXPROCS(maxThreads)
+	if virtual {
+		config.Clock = clinic.NewVirtualClock(time.Now())
+	}
+
+	// Tells whether every event was written out, once the run is over
+	closeEvents := func() error { return nil }
+	switch events {
+	case "console":
+		config.Sink = clinic.NewConsoleSink(os.Stderr)
+		if dashboard {
+			config.Sink = clinic.Sinks{}
+		}
+	case "jsonl":
+		out := os.Stdout
+		if dashboard && eventsFile == "-" {
+			fmt.Fprintln(os.Stderr, "clinic: the dashboard needs the jsonl events written to a -events-file")
+			os.Exit(2)
+		}
+		var file *os.File
+		if eventsFile != "-" {
+			var err error
+			if file, err = os.Create(eventsFile); err != nil {
+				log.Fatal(err)
+			}
+			out = file
+		}
+		sink := clinic.NewJSONLSink(out)
+		closeEvents = func() error {
+			if file == nil {
+				return sink.Err()
+			}
+			return errors.Join(sink.Err(), file.Close())
+		}
+		config.Sink = sink
+	default:
+		fmt.Fprintf(os.Stderr, "clinic: unknown events format %q\n", events)
+		os.Exit(2)
+	}
+
+	if metricsAddress != "" {
+		metrics := clinic.NewMetrics()
+		serve(metricsAddress, metrics)
+		config.Sink = clinic.Sinks{config.Sink, metrics}
+	}
+
+	// closes the clinic early on Ctrl+C
+	ctx, closeClinic := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer closeClinic()
+
+	if timeout > 0 {
+		var closingTime context.CancelFunc
+		ctx, closingTime = context.WithTimeout(ctx, timeout)
+		defer closingTime()
+	}
+
+	// The summary is printed once the dashboard gives the terminal back
+	hideDashboard := func() {}
+	if dashboard {
+		screen := clinic.NewDashboard(os.Stdout, config)
+		config.Sink = clinic.Sinks{config.Sink, screen}
+
+		showing, hide := context.WithCancel(context.Background())
+		shown := make(chan struct{})
+		go func() {
+			defer close(shown)
+			screen.Show(showing, 100*time.Millisecond)
+		}()
+		hideDashboard = func() {
+			hide()
+			<-shown
+		}
+	}
+
+	report, err := clinic.Run(ctx, config)
+	hideDashboard()
+	if err := errors.Join(err, closeEvents()); err != nil {
+		log.Fatal(err)
+	}
+
+	clinic.ReportLog(report)
+}
+
+func find(name string) (scenario, bool) {
+	for _, candidate := range scenarios {
+		if candidate.name == name {
+			return candidate, true
+		}
+	}
+	return scenario{}, false
+}
+
+/**
+ * Serves the metrics on /metrics in the background, for as long as the command runs
+ */
+func serve(address string, metrics http.Handler) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go http.Serve(listener, mux)
+
+	log.Printf("Serving metrics on http://%s/metrics", listener.Addr())
+}
+
+/**
+ * A scenario described by a scenario file, its settings becoming the flags' defaults
+ */
+func load(path string) (scenario, error) {
+	file, err := clinic.LoadScenarioFile(path)
+	if err != nil {
+		return scenario{}, err
+	}
+	config, timeout, err := file.Config()
+	if err != nil {
+		return scenario{}, err
+	}
+	return scenario{name: path, config: config, timeout: timeout}, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: clinic <scenario> [flags]")
+	fmt.Fprintln(os.Stderr, "       clinic run <scenario file> [flags]")
+	fmt.Fprintln(os.Stderr, "       clinic bench [flags]")
+	fmt.Fprintln(os.Stderr, "       clinic interleave [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "scenarios:")
+	for _, scenario := range scenarios {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", scenario.name, scenario.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run `clinic <scenario> -h` to list the flags of a scenario")
+}
+
+/** flags **********************************************************/
+
+/**
+ * A flag setting a treatment duration model
+ */
+type durationFlag struct {
+	model *clinic.DurationModel
+}
+
+func (flag durationFlag) String() string {
+	if flag.model == nil || *flag.model == nil {
+		return ""
+	}
+	return fmt.Sprint(*flag.model)
+}
+
+func (flag durationFlag) Set(spec string) error {
+	model, err := clinic.ParseDurationModel(spec)
+	if err != nil {
+		return err
+	}
+	*flag.model = model
+	return nil
+}
+
+/**
+ * A flag setting the treatment mix
+ */
+type treatmentsFlag struct {
+	mix *clinic.TreatmentMix
+}
+
+func (flag treatmentsFlag) String() string {
+	if flag.mix == nil {
+		return ""
+	}
+	return flag.mix.String()
+}
+
+func (flag treatmentsFlag) Set(spec string) error {
+	mix, err := clinic.ParseTreatmentMix(spec)
+	if err != nil {
+		return err
+	}
+	*flag.mix = mix
+	return nil
+}
+
+/**
+ * A flag setting the patient arrival process
+ */
+type arrivalFlag struct {
+	process *clinic.ArrivalProcess
+}
+
+func (flag arrivalFlag) String() string {
+	if flag.process == nil || *flag.process == nil {
+		return ""
+	}
+	return fmt.Sprint(*flag.process)
+}
+
+func (flag arrivalFlag) Set(spec string) error {
+	process, err := clinic.ParseArrivalProcess(spec)
+	if err != nil {
+		return err
+	}
+	*flag.process = process
+	return nil
+}
+
+/**
+ * A flag setting the scheduling policy
+ */
+type policyFlag struct {
+	policy *clinic.SchedulingPolicy
+}
+
+func (flag policyFlag) String() string {
+	if flag.policy == nil || *flag.policy == nil {
+		return ""
+	}
+	return fmt.Sprint(*flag.policy)
+}
+
+func (flag policyFlag) Set(spec string) error {
+	policy, err := clinic.ParseSchedulingPolicy(spec)
+	if err != nil {
+		return err
+	}
+	*flag.policy = policy
+	return nil
+}
+
+/**
+ * A flag booking patients ahead, as count,interval[,first]
+ */
+type appointmentsFlag struct {
+	booked *[]clinic.Appointment
+}
+
+func (flag appointmentsFlag) String() string {
+	if flag.booked == nil || len(*flag.booked) == 0 {
+		return ""
+	}
+	booked := *flag.booked
+	interval := time.Duration(0)
+	if len(booked) > 1 {
+		interval = booked[1].Slot - booked[0].Slot
+	}
+	return fmt.Sprintf("%d,%v,%v", len(booked), interval, booked[0].Slot)
+}
+
+func (flag appointmentsFlag) Set(spec string) error {
+	booked, err := clinic.ParseSlots(spec, 0)
+	if err != nil {
+		return err
+	}
+	*flag.booked = booked
+	return nil
+}
+
+/**
+ * A flag setting the priority levels, as name:capacity:patients,...
+ */
+type levelsFlag struct {
+	levels *[]clinic.Level
+}
+
+func (flag levelsFlag) String() string {
+	if flag.levels == nil {
+		return ""
+	}
+	specs := make([]string, len(*flag.levels))
+	for i, level := range *flag.levels {
+		specs[i] = fmt.Sprintf("%s:%d:%d", level.Name, level.Capacity, level.Patients)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (flag levelsFlag) Set(spec string) error {
+	var levels []clinic.Level
+	for _, level := range strings.Split(spec, ",") {
+		fields := strings.Split(level, ":")
+		if len(fields) != 3 {
+			return fmt.Errorf("level %q is not name:capacity:patients", level)
+		}
+		capacity, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		patients, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return err
+		}
+		levels = append(levels, clinic.Level{Name: fields[0], Capacity: capacity, Patients: patients})
+	}
+	*flag.levels = levels
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package cpu
+
+import "time"
+
+/**
+ * CPU time is only measured on unix systems
+ */
+func Time() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package cpu
+
+import (
+	"syscall"
+	"time"
+)
+
+/**
+ * CPU time (user and system) the process used so far
+ */
+func Time() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
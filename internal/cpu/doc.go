@@ -0,0 +1,5 @@
+/**
+ * Package cpu measures how much CPU time the process used, for the idle
+ * clinic to be checked against by the clinic bench command and the tests.
+ */
+package cpu